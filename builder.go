@@ -0,0 +1,78 @@
+// Copyright 2014 Ethan Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package todo
+
+import (
+	"fmt"
+	"time"
+)
+
+// A TaskBuilder constructs a Task one field at a time.
+// Dates are given as strings in DateFormat and are not
+// checked until Build is called.
+type TaskBuilder struct {
+	task  Task
+	due   string
+	start string
+}
+
+// NewTask returns a builder for a task with the given title.
+func NewTask(title string) *TaskBuilder {
+	return &TaskBuilder{task: Task{Title: title}}
+}
+
+// Due sets the due date of the task.
+func (b *TaskBuilder) Due(date string) *TaskBuilder {
+	b.due = date
+	return b
+}
+
+// Start sets the start date of the task.
+func (b *TaskBuilder) Start(date string) *TaskBuilder {
+	b.start = date
+	return b
+}
+
+// Tag adds a tag to the task.
+func (b *TaskBuilder) Tag(tag string) *TaskBuilder {
+	b.task.Tags = append(b.task.Tags, tag)
+	return b
+}
+
+// Context adds a context to the task.
+func (b *TaskBuilder) Context(context string) *TaskBuilder {
+	b.task.Contexts = append(b.task.Contexts, context)
+	return b
+}
+
+// Done sets whether the task is completed.
+func (b *TaskBuilder) Done(done bool) *TaskBuilder {
+	b.task.Done = done
+	return b
+}
+
+// Build returns the constructed task, or an error if either date
+// does not match DateFormat.
+func (b *TaskBuilder) Build() (Task, error) {
+	t := b.task
+	t.Tags = append([]string(nil), b.task.Tags...)
+	t.Contexts = append([]string(nil), b.task.Contexts...)
+
+	var err error
+	if len(b.due) > 0 {
+		t.Due, err = time.ParseInLocation(DateFormat, b.due, time.Local)
+		if err != nil {
+			return Task{}, fmt.Errorf("todo: bad due date %q", b.due)
+		}
+	}
+	if len(b.start) > 0 {
+		t.Start, err = time.ParseInLocation(DateFormat, b.start, time.Local)
+		if err != nil {
+			return Task{}, fmt.Errorf("todo: bad start date %q", b.start)
+		}
+	}
+	t.Raw = t.UnParse()
+	return t, nil
+}
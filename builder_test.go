@@ -0,0 +1,43 @@
+// Copyright 2014 Ethan Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package todo
+
+import "testing"
+
+func TestBuilder(t *testing.T) {
+	task, err := NewTask("write novel").
+		Due("2015-12-31").
+		Start("2015-12-30").
+		Tag("art").
+		Context("desk").
+		Done(true).
+		Build()
+	if err != nil {
+		t.Fatalf("unexpected build error %v", err)
+	}
+	expect := "x write novel 2015-12-31 s:2015-12-30 @desk +art"
+	if got := task.UnParse(); got != expect {
+		t.Errorf("Got %v, expected %v", got, expect)
+	}
+	if task.Raw != expect {
+		t.Errorf("Got raw %v, expected %v", task.Raw, expect)
+	}
+
+	errors := []struct {
+		b      *TaskBuilder
+		expect string
+	}{
+		{NewTask("a").Due("soon"), `todo: bad due date "soon"`},
+		{NewTask("a").Due("2015-1-1").Start("2015-13-1"), `todo: bad start date "2015-13-1"`},
+	}
+	for _, cas := range errors {
+		_, err := cas.b.Build()
+		if err == nil {
+			t.Errorf("Got no error (expected %v)", cas.expect)
+		} else if err.Error() != cas.expect {
+			t.Errorf("Got %v (expected %v)", err, cas.expect)
+		}
+	}
+}
@@ -5,8 +5,10 @@
 package main
 
 import (
+	"bufio"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/millere/todo"
 )
@@ -26,15 +28,7 @@ func runArchive(cmd *Command, conf config, args []string) {
 		fmt.Println(err)
 		return
 	}
-	var active todo.TaskList
-	var archive todo.TaskList
-	for _, task := range inFile {
-		if task.Done {
-			archive = append(archive, task)
-		} else {
-			active = append(active, task)
-		}
-	}
+	archive, _ := inFile.Partition()
 
 	if len(archive) == 0 {
 		fmt.Println("No tasks archived.")
@@ -48,26 +42,18 @@ func runArchive(cmd *Command, conf config, args []string) {
 	}
 	defer arcFile.Close()
 	for _, t := range archive {
-		_, err := fmt.Fprintln(arcFile, t.Raw)
+		_, err := fmt.Fprintln(arcFile, t.UnParseOriginal())
 		if err != nil {
 			fmt.Println(err)
 			return
 		}
 	}
 
-	file, err := os.Create(conf.Todos)
+	err = writeTodoFile(conf.Todos, inFile, func(t *todo.Task) bool { return !t.Done })
 	if err != nil {
 		fmt.Println("Couldn't modify todo file:", err)
 		return
 	}
-	defer file.Close()
-	for _, t := range active {
-		_, err := fmt.Fprintln(file, t.Raw)
-		if err != nil {
-			fmt.Println(err)
-			return
-		}
-	}
 
 	fmt.Printf("Archived %d task", len(archive))
 	if len(archive) != 1 {
@@ -85,8 +71,55 @@ func readTodoFile(f string) (todo.TaskList, error) {
 	defer file.Close()
 	todos, err := todo.FromReader(file)
 	if err != nil {
-		fmt.Println(err)
 		return nil, err
 	}
 	return todos, nil
 }
+
+// writeTodoFile rewrites the todo file at f with the tasks in todos
+// for which keep returns true, each in its original place. Comment and
+// blank lines are kept as they were; the lines and notes of tasks not
+// kept are dropped.
+func writeTodoFile(f string, todos todo.TaskList, keep func(*todo.Task) bool) error {
+	in, err := os.Open(f)
+	if err != nil {
+		return err
+	}
+	var lines []string
+	s := bufio.NewScanner(in)
+	for s.Scan() {
+		lines = append(lines, strings.TrimSuffix(s.Text(), "\r"))
+	}
+	in.Close()
+	if err := s.Err(); err != nil {
+		return err
+	}
+
+	file, err := os.Create(f)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	w := bufio.NewWriter(file)
+	// whether indented lines are notes of the last task, written with it
+	inTask := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		t, ok := todos.Line(i + 1)
+		switch {
+		case ok:
+			inTask = true
+			if keep(t) {
+				fmt.Fprintln(w, t.UnParseOriginal())
+			}
+		case len(trimmed) == 0 || trimmed[0] == '#':
+			fmt.Fprintln(w, line)
+		case inTask && trimmed != line:
+			// a note, written with its task
+		default:
+			inTask = false
+			fmt.Fprintln(w, line)
+		}
+	}
+	return w.Flush()
+}
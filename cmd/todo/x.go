@@ -6,7 +6,6 @@ package main
 
 import (
 	"fmt"
-	"os"
 	"strconv"
 
 	"github.com/millere/todo"
@@ -28,40 +27,26 @@ func runX(cmd *Command, conf config, args []string) {
 		}
 		toMark = append(toMark, mark)
 	}
-	todoFile, _ := os.Open(conf.Todos)
-	todos, err := todo.FromReader(todoFile)
+	todos, err := readTodoFile(conf.Todos)
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
 
 	didWork := false
-	for i := range todos {
-		//BUG(@millere): off-by-one error
-		if elementOf(toMark, i+1) {
-			todos[i].Done = true
+	for _, n := range toMark {
+		// tasks are numbered by line, as in list
+		if t, ok := todos.Line(n); ok {
+			t.Complete()
 			didWork = true
 		}
 	}
 
 	if didWork {
-		file, err := os.Create(conf.Todos)
+		// TODO: if -s sort before writing
+		err := writeTodoFile(conf.Todos, todos, func(*todo.Task) bool { return true })
 		if err != nil {
 			fmt.Println("Couldn't modify todo file:", err)
-			return
-		}
-		// TODO: if -s sort before writing
-		for i := range todos {
-			fmt.Fprintln(file, todos[i].UnParse())
-		}
-	}
-}
-
-func elementOf(as []int, a int) bool {
-	for _, b := range as {
-		if b == a {
-			return true
 		}
 	}
-	return false
 }
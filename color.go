@@ -0,0 +1,50 @@
+// Copyright 2014 Ethan Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package todo
+
+import "strings"
+
+// NoColor disables the escape codes in ColorString, for example when
+// output is not a terminal.
+var NoColor bool
+
+// ANSI escape codes used by ColorString.
+const (
+	ansiReset   = "\x1b[0m"
+	ansiGray    = "\x1b[90m"
+	ansiRed     = "\x1b[31m"
+	ansiGreen   = "\x1b[32m"
+	ansiYellow  = "\x1b[33m"
+	ansiBlue    = "\x1b[34m"
+	ansiMagenta = "\x1b[35m"
+)
+
+var tokenColors = map[TokenKind]string{
+	DoneToken:          ansiGray,
+	PriorityToken:      ansiRed,
+	FlagToken:          ansiRed,
+	CompletedDateToken: ansiYellow,
+	CreatedDateToken:   ansiYellow,
+	DueDateToken:       ansiYellow,
+	StartDateToken:     ansiYellow,
+	ContextToken:       ansiGreen,
+	TagToken:           ansiBlue,
+	RecurrenceToken:    ansiMagenta,
+}
+
+// ColorString is like UnParse, but with the done marker, priority,
+// dates, contexts and tags wrapped in ANSI color codes, unless
+// NoColor is set.
+func (t Task) ColorString() string {
+	tokens := t.Tokens()
+	texts := make([]string, len(tokens))
+	for i, tok := range tokens {
+		texts[i] = tok.Text
+		if c, ok := tokenColors[tok.Kind]; ok && !NoColor {
+			texts[i] = c + tok.Text + ansiReset
+		}
+	}
+	return strings.Join(texts, " ")
+}
@@ -0,0 +1,45 @@
+// Copyright 2014 Ethan Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package todo
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestColorString(t *testing.T) {
+	defer func(old bool) { NoColor = old }(NoColor)
+
+	todo, err := Parse("x (A) 2014-3-6 foo 2014-3-5 @home +work")
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+
+	NoColor = false
+	got := todo.ColorString()
+	for _, want := range []string{
+		ansiGray + "x" + ansiReset,
+		ansiRed + "(A)" + ansiReset,
+		ansiYellow + "2014-3-5" + ansiReset,
+		ansiGreen + "@home" + ansiReset,
+		ansiBlue + "+work" + ansiReset,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Got %q, expected it to contain %q", got, want)
+		}
+	}
+	if strings.Contains(got, ansiGray+"foo") || !strings.Contains(got, " foo ") {
+		t.Errorf("Got %q, expected the title uncolored", got)
+	}
+
+	NoColor = true
+	got = todo.ColorString()
+	if strings.Contains(got, "\x1b") {
+		t.Errorf("Got %q with NoColor set", got)
+	}
+	if got != todo.UnParse() {
+		t.Errorf("Got %q (expected %q)", got, todo.UnParse())
+	}
+}
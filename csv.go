@@ -0,0 +1,81 @@
+// Copyright 2014 Ethan Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package todo
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// csvHeader names the columns written by ToCSV.
+var csvHeader = []string{"done", "title", "due", "start", "tags", "contexts"}
+
+// ToCSV writes the list to w as CSV, with a header row followed by
+// one row per task. Tags and contexts are joined with semicolons.
+func (ts TaskList) ToCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, t := range ts {
+		err := cw.Write([]string{
+			strconv.FormatBool(t.Done),
+			t.Title,
+			formatTimed(t.Due, t.dueTime),
+			formatTimed(t.Start, t.startTime),
+			strings.Join(t.Tags, ";"),
+			strings.Join(t.Contexts, ";"),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// FromCSV reads a list written by ToCSV. The header row is optional.
+func FromCSV(r io.Reader) (TaskList, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) > 0 && len(records[0]) > 0 && records[0][0] == csvHeader[0] {
+		records = records[1:]
+	}
+
+	var ret TaskList
+	for i, rec := range records {
+		if len(rec) != len(csvHeader) {
+			return nil, fmt.Errorf("todo: csv record %v has %v fields, expected %v", i+1, len(rec), len(csvHeader))
+		}
+		var t Task
+		if t.Done, err = strconv.ParseBool(rec[0]); err != nil {
+			return nil, fmt.Errorf("todo: csv record %v has bad done value %q", i+1, rec[0])
+		}
+		t.Title = rec[1]
+		if t.Due, t.dueTime, err = parseTimed(rec[2]); err != nil {
+			return nil, fmt.Errorf("todo: csv record %v has bad due date %q", i+1, rec[2])
+		}
+		if t.Start, t.startTime, err = parseTimed(rec[3]); err != nil {
+			return nil, fmt.Errorf("todo: csv record %v has bad start date %q", i+1, rec[3])
+		}
+		if len(rec[4]) > 0 {
+			t.Tags = strings.Split(rec[4], ";")
+		}
+		if len(rec[5]) > 0 {
+			t.Contexts = strings.Split(rec[5], ";")
+		}
+		t.Raw = t.UnParse()
+		t.index = i + 1
+		ret = append(ret, t)
+	}
+	return ret, nil
+}
@@ -0,0 +1,67 @@
+// Copyright 2014 Ethan Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package todo
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCSV(t *testing.T) {
+	in := "feed cats, dogs 2014-12-23 s:2014-12-20 @home @barn +pets\nx eat \"lunch\"\nwrite novel +art\ncall mom due:2024-01-02T15:00Z\n"
+	l, err := FromReader(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := l.ToCSV(&buf); err != nil {
+		t.Fatalf("unexpected write error %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "done,title,due,start,tags,contexts\n") {
+		t.Errorf("Got %q, expected a header row", buf.String())
+	}
+
+	again, err := FromCSV(&buf)
+	if err != nil {
+		t.Fatalf("unexpected read error %v", err)
+	}
+	for i := range l {
+		l[i].original = ""
+		l[i].order = nil
+	}
+	if !reflect.DeepEqual(again, l) {
+		t.Errorf("Got %v, expected %v", again, l)
+	}
+
+	noHeader := "false,milk,2014-1-2,,,store\n"
+	l, err = FromCSV(strings.NewReader(noHeader))
+	if err != nil {
+		t.Fatalf("unexpected read error %v", err)
+	}
+	if len(l) != 1 || l[0].UnParse() != "milk 2014-1-2 @store" {
+		t.Errorf("Got %v, expected milk", l)
+	}
+
+	errors := []struct {
+		in     string
+		expect string
+	}{
+		{"false,milk\n", "todo: csv record 1 has 2 fields, expected 6"},
+		{"done,title,due,start,tags,contexts\nfalse,a,,,,\nfalse,b,,,\n", "todo: csv record 2 has 5 fields, expected 6"},
+		{"maybe,milk,,,,\n", `todo: csv record 1 has bad done value "maybe"`},
+		{"false,milk,soon,,,\n", `todo: csv record 1 has bad due date "soon"`},
+	}
+	for _, cas := range errors {
+		_, err := FromCSV(strings.NewReader(cas.in))
+		if err == nil {
+			t.Errorf("On case %q, got no error (expected %v)", cas.in, cas.expect)
+		} else if err.Error() != cas.expect {
+			t.Errorf("On case %q, got %v (expected %v)", cas.in, err, cas.expect)
+		}
+	}
+}
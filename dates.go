@@ -0,0 +1,270 @@
+// Copyright 2014 Ethan Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package todo
+
+import (
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Now returns the current time. It is used to resolve relative
+// dates, and may be replaced to make results deterministic.
+var Now = time.Now
+
+// today returns midnight of the current day in the local time zone.
+func today() time.Time {
+	y, m, d := Now().In(time.Local).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+}
+
+// relativeDate resolves the keywords today, tomorrow, and yesterday,
+// and ISO weeks of the current year as weekDate does.
+func relativeDate(token string) (time.Time, bool) {
+	switch token {
+	case "today":
+		return today(), true
+	case "tomorrow":
+		return today().AddDate(0, 0, 1), true
+	case "yesterday":
+		return today().AddDate(0, 0, -1), true
+	}
+	return weekDate(token)
+}
+
+// weekDate resolves W followed by one or two digits to the Monday of
+// that ISO week of the current year.
+func weekDate(token string) (time.Time, bool) {
+	if len(token) < 2 || len(token) > 3 || token[0] != 'W' {
+		return time.Time{}, false
+	}
+	week, err := strconv.Atoi(token[1:])
+	if err != nil || week < 1 || token[1] == '+' || token[1] == '-' {
+		return time.Time{}, false
+	}
+
+	// January 4th is always in week 1
+	year := today().Year()
+	jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, time.Local)
+	monday := jan4.AddDate(0, 0, -(int(jan4.Weekday())+6)%7)
+	date := monday.AddDate(0, 0, 7*(week-1))
+	if y, w := date.ISOWeek(); y != year || w != week {
+		return time.Time{}, false
+	}
+	return date, true
+}
+
+// dateTimeLayouts are the layouts of dates with times accepted in due:
+// and s: values: RFC 3339, with or without seconds.
+var dateTimeLayouts = []string{time.RFC3339, "2006-01-02T15:04Z07:00"}
+
+// dateTime parses an RFC 3339 date and time, which may leave out the
+// seconds.
+func dateTime(s string) (time.Time, error) {
+	var err error
+	for _, layout := range dateTimeLayouts {
+		var d time.Time
+		if d, err = time.Parse(layout, s); err == nil {
+			return d, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// formatDateTime formats d as RFC 3339, leaving out zero seconds.
+func formatDateTime(d time.Time) string {
+	if d.Second() == 0 && d.Nanosecond() == 0 {
+		return d.Format(dateTimeLayouts[1])
+	}
+	return d.Format(time.RFC3339Nano)
+}
+
+// sameDay reports whether a and b fall on the same calendar day
+// in the local time zone.
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.In(time.Local).Date()
+	by, bm, bd := b.In(time.Local).Date()
+	return ay == by && am == bm && ad == bd
+}
+
+// Overdue reports whether the task is not done and was due before today.
+func (t Task) Overdue() bool {
+	return !t.Done && !t.Due.IsZero() && t.Due.Before(today())
+}
+
+// DueToday reports whether the task is due today, ignoring the time of day.
+func (t Task) DueToday() bool {
+	return !t.Due.IsZero() && sameDay(t.Due, today())
+}
+
+// DaysUntilDue returns the number of calendar days from today until
+// the task is due, negative if it is overdue. It returns false if the
+// task has no due date.
+func (t Task) DaysUntilDue() (int, bool) {
+	if t.Due.IsZero() {
+		return 0, false
+	}
+	return daysBetween(today(), t.Due), true
+}
+
+// Active reports whether the task has started as of on. Tasks with
+// a start date after on are deferred; tasks with no start date are
+// always active.
+func (t Task) Active(on time.Time) bool {
+	return t.Start.IsZero() || !t.Start.After(on)
+}
+
+// FilterActive returns a new tasklist containing all of the tasks
+// that are active as of on.
+func (ts TaskList) FilterActive(on time.Time) TaskList {
+	return ts.FilterFunc(func(t Task) bool { return t.Active(on) })
+}
+
+// daysBetween returns the number of calendar days from a to b,
+// ignoring the time of day.
+func daysBetween(a, b time.Time) int {
+	ay, am, ad := a.In(time.Local).Date()
+	by, bm, bd := b.In(time.Local).Date()
+	d := time.Date(by, bm, bd, 0, 0, 0, 0, time.UTC).Sub(time.Date(ay, am, ad, 0, 0, 0, 0, time.UTC))
+	return int(d.Hours() / 24)
+}
+
+// ActiveOn returns a new tasklist containing the not done tasks whose
+// span from start to due date includes day, ignoring the time of day.
+// A task with no start date began long ago, and one with no due date
+// runs on indefinitely.
+func (ts TaskList) ActiveOn(day time.Time) TaskList {
+	return ts.FilterFunc(func(t Task) bool {
+		return !t.Done &&
+			(t.Start.IsZero() || daysBetween(t.Start, day) >= 0) &&
+			(t.Due.IsZero() || daysBetween(day, t.Due) >= 0)
+	})
+}
+
+// Age returns how long ago the task was created, or zero if it has
+// no creation date.
+func (t Task) Age() time.Duration {
+	if t.Created.IsZero() {
+		return 0
+	}
+	return Now().Sub(t.Created)
+}
+
+// AgeDays returns the number of calendar days since the task was
+// created, or zero if it has no creation date.
+func (t Task) AgeDays() int {
+	if t.Created.IsZero() {
+		return 0
+	}
+	return daysBetween(t.Created, Now())
+}
+
+// DueWithin returns a new tasklist containing all of the tasks that
+// are not done and are due between the start of today and d from now,
+// inclusive. A negative d gives an empty list.
+func (ts TaskList) DueWithin(d time.Duration) TaskList {
+	if d < 0 {
+		return nil
+	}
+	from, to := today(), Now().Add(d)
+	return ts.FilterFunc(func(t Task) bool {
+		return !t.Done && !t.Due.IsZero() && !t.Due.Before(from) && !t.Due.After(to)
+	})
+}
+
+// DueBuckets groups the list by when tasks are due, relative to today.
+// Not done tasks are put under "overdue", "today", "thisweek" (after
+// today, through Sunday), "later", or "none" if they have no due date.
+// Done tasks are put under "done". Empty buckets are omitted.
+func (ts TaskList) DueBuckets() map[string]TaskList {
+	// weeks start on Monday, so this many days are left until Sunday
+	left := (7 - int(Now().In(time.Local).Weekday())) % 7
+
+	ret := make(map[string]TaskList)
+	for _, t := range ts {
+		var bucket string
+		switch {
+		case t.Done:
+			bucket = "done"
+		case t.Due.IsZero():
+			bucket = "none"
+		case t.Overdue():
+			bucket = "overdue"
+		case t.DueToday():
+			bucket = "today"
+		case daysBetween(today(), t.Due) <= left:
+			bucket = "thisweek"
+		default:
+			bucket = "later"
+		}
+		ret[bucket] = append(ret[bucket], t)
+	}
+	return ret
+}
+
+// A DueGroup is the tasks due on one day.
+type DueGroup struct {
+	Date  time.Time // midnight of the day, or zero for undated tasks
+	Tasks TaskList
+}
+
+// ByDueDate groups the list by the day tasks are due, in chronological
+// order, with a final group for tasks with no due date. Each group's
+// tasks are sorted as by Sorted.
+func (ts TaskList) ByDueDate() []DueGroup {
+	var ret []DueGroup
+	groups := make(map[time.Time]int)
+	for _, t := range ts.Sorted() {
+		var day time.Time
+		if !t.Due.IsZero() {
+			y, m, d := t.Due.In(time.Local).Date()
+			day = time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+		}
+		i, ok := groups[day]
+		if !ok {
+			i = len(ret)
+			groups[day] = i
+			ret = append(ret, DueGroup{Date: day})
+		}
+		ret[i].Tasks = append(ret[i].Tasks, t)
+	}
+	sort.Sort(byGroupDate(ret))
+	return ret
+}
+
+type byGroupDate []DueGroup
+
+func (g byGroupDate) Len() int      { return len(g) }
+func (g byGroupDate) Swap(i, j int) { g[i], g[j] = g[j], g[i] }
+func (g byGroupDate) Less(i, j int) bool {
+	b, _ := before(g[i].Date, g[j].Date)
+	return b
+}
+
+// Shift moves the task's due and start dates, if set, forward by d,
+// and marks it changed. If d is a whole number of days, dates move by
+// calendar days, so they keep their time of day across daylight
+// saving changes.
+func (t *Task) Shift(d time.Duration) {
+	shift := func(date time.Time) time.Time {
+		if date.IsZero() {
+			return date
+		}
+		if d%(24*time.Hour) == 0 {
+			return date.AddDate(0, 0, int(d/(24*time.Hour)))
+		}
+		return date.Add(d)
+	}
+	t.Due = shift(t.Due)
+	t.Start = shift(t.Start)
+	t.dirty = true
+}
+
+// Shift shifts every task in the list by d, in place.
+func (ts TaskList) Shift(d time.Duration) {
+	for i := range ts {
+		ts[i].Shift(d)
+	}
+}
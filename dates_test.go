@@ -0,0 +1,525 @@
+// Copyright 2014 Ethan Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package todo
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setNow replaces Now with a fixed time until the returned
+// function is called.
+func setNow(now time.Time) func() {
+	old := Now
+	Now = func() time.Time { return now }
+	return func() { Now = old }
+}
+
+func TestRelativeDates(t *testing.T) {
+	cases := []struct {
+		now    time.Time
+		in     string
+		expect string
+	}{
+		{time.Date(2014, 3, 15, 12, 0, 0, 0, time.Local), "foo today", "foo 2014-3-15"},
+		{time.Date(2014, 3, 15, 12, 0, 0, 0, time.Local), "foo tomorrow", "foo 2014-3-16"},
+		{time.Date(2014, 3, 15, 12, 0, 0, 0, time.Local), "foo yesterday", "foo 2014-3-14"},
+		{time.Date(2014, 1, 31, 23, 0, 0, 0, time.Local), "foo tomorrow", "foo 2014-2-1"},
+		{time.Date(2014, 2, 28, 0, 0, 0, 0, time.Local), "foo tomorrow", "foo 2014-3-1"},
+		{time.Date(2014, 12, 31, 8, 0, 0, 0, time.Local), "foo tomorrow", "foo 2015-1-1"},
+		{time.Date(2014, 3, 1, 8, 0, 0, 0, time.Local), "foo yesterday", "foo 2014-2-28"},
+		{time.Date(2014, 3, 1, 8, 0, 0, 0, time.Local), "foo Tomorrow", "foo Tomorrow"},
+		{time.Date(2014, 3, 15, 12, 0, 0, 0, time.Local), "x today", "x due:2014-3-15"},
+		{time.Date(2014, 3, 15, 12, 0, 0, 0, time.Local), "x 2014-3-2 tomorrow", "x 2014-3-2 due:2014-3-16"},
+		{time.Date(2014, 3, 15, 12, 0, 0, 0, time.Local), "x 2014-3-2 2014-3-1 yesterday", "x 2014-3-2 2014-3-1 2014-3-14"},
+	}
+
+	for _, cas := range cases {
+		restore := setNow(cas.now)
+		todo, err := Parse(cas.in)
+		got := todo.UnParse()
+		again, aerr := Parse(got)
+		restore()
+		if err != nil {
+			t.Errorf("On case %v, unexpected parse error %v", cas.in, err)
+			continue
+		}
+		if got != cas.expect {
+			t.Errorf("On case %v at %v, got %v (expected %v)", cas.in, cas.now, got, cas.expect)
+		}
+		if aerr != nil || !again.Due.Equal(todo.Due) || !again.Completed.Equal(todo.Completed) || !again.Created.Equal(todo.Created) {
+			t.Errorf("On case %v, %v reparsed to %v, %v", cas.in, got, again, aerr)
+		}
+	}
+}
+
+func TestOverdue(t *testing.T) {
+	at := func(d, hour int) time.Time {
+		return time.Date(2014, 3, d, hour, 0, 0, 0, time.Local)
+	}
+
+	cases := []struct {
+		in       string
+		now      time.Time
+		overdue  bool
+		dueToday bool
+	}{
+		{"foo 2014-3-14", at(15, 0), true, false},
+		{"foo 2014-3-15", at(15, 0), false, true},
+		{"foo 2014-3-15", at(15, 23), false, true},
+		{"foo 2014-3-15", at(16, 0), true, false},
+		{"foo 2014-3-16", at(15, 23), false, false},
+		{"x foo 2014-3-14", at(15, 0), false, false},
+		{"x foo 2014-3-15", at(15, 0), false, true},
+		{"foo", at(15, 0), false, false},
+	}
+
+	for _, cas := range cases {
+		todo, err := Parse(cas.in)
+		if err != nil {
+			t.Fatalf("On case %v, unexpected parse error %v", cas.in, err)
+		}
+		restore := setNow(cas.now)
+		if got := todo.Overdue(); got != cas.overdue {
+			t.Errorf("On case %v at %v, got overdue %v (expected %v)", cas.in, cas.now, got, cas.overdue)
+		}
+		if got := todo.DueToday(); got != cas.dueToday {
+			t.Errorf("On case %v at %v, got due today %v (expected %v)", cas.in, cas.now, got, cas.dueToday)
+		}
+		restore()
+	}
+}
+
+func TestComplete(t *testing.T) {
+	restore := setNow(time.Date(2014, 3, 15, 9, 0, 0, 0, time.Local))
+	todo, err := Parse("(A) buy milk 2014-3-20")
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+	todo.Complete()
+	restore()
+	expect := "x (A) 2014-3-15 buy milk 2014-3-20"
+	if got := todo.UnParse(); got != expect {
+		t.Errorf("Got %v, expected %v", got, expect)
+	}
+
+	restore = setNow(time.Date(2014, 3, 16, 9, 0, 0, 0, time.Local))
+	todo.Complete()
+	restore()
+	if got := todo.UnParse(); got != expect {
+		t.Errorf("Completing twice got %v, expected %v", got, expect)
+	}
+
+	todo.Reopen()
+	expect = "(A) buy milk 2014-3-20"
+	if got := todo.UnParse(); got != expect || !todo.Completed.IsZero() {
+		t.Errorf("Reopening got %v, expected %v", got, expect)
+	}
+}
+
+func TestActive(t *testing.T) {
+	l, err := FromReader(strings.NewReader("a s:2014-3-16\nb s:2014-3-15\nc\nd s:2014-3-17\n"))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+
+	cases := []struct {
+		on     time.Time
+		expect string
+	}{
+		{time.Date(2014, 3, 15, 0, 0, 0, 0, time.Local), "bc"},
+		{time.Date(2014, 3, 15, 23, 59, 0, 0, time.Local), "bc"},
+		{time.Date(2014, 3, 16, 0, 0, 0, 0, time.Local), "abc"},
+		{time.Date(2014, 3, 16, 12, 0, 0, 0, time.Local), "abc"},
+		{time.Date(2014, 3, 17, 0, 0, 0, 0, time.Local), "abcd"},
+	}
+
+	for _, cas := range cases {
+		var got string
+		for _, task := range l.FilterActive(cas.on) {
+			got += task.Title
+		}
+		if got != cas.expect {
+			t.Errorf("On %v, got %v (expected %v)", cas.on, got, cas.expect)
+		}
+	}
+}
+
+func TestAge(t *testing.T) {
+	cases := []struct {
+		in   string
+		now  time.Time
+		days int
+		age  time.Duration
+	}{
+		{"x 2014-3-2 2014-2-27 foo", time.Date(2014, 3, 2, 0, 0, 0, 0, time.Local), 3, 0},
+		{"x 2014-3-2 2014-2-27 foo", time.Date(2014, 3, 2, 18, 0, 0, 0, time.Local), 3, 0},
+		{"x 2016-3-2 2016-2-27 foo", time.Date(2016, 3, 2, 0, 0, 0, 0, time.Local), 4, 0},
+		{"x 2015-1-2 2014-12-30 foo", time.Date(2015, 1, 2, 12, 0, 0, 0, time.Local), 3, 0},
+		{"x 2014-3-2 2014-3-2 foo", time.Date(2014, 3, 2, 6, 0, 0, 0, time.Local), 0, 6 * time.Hour},
+		{"x 2014-3-2 foo", time.Date(2014, 3, 2, 6, 0, 0, 0, time.Local), 0, 0},
+	}
+
+	for _, cas := range cases {
+		todo, err := Parse(cas.in)
+		if err != nil {
+			t.Fatalf("On case %v, unexpected parse error %v", cas.in, err)
+		}
+		restore := setNow(cas.now)
+		if got := todo.AgeDays(); got != cas.days {
+			t.Errorf("On case %v at %v, got %v days (expected %v)", cas.in, cas.now, got, cas.days)
+		}
+		if got := todo.Age(); cas.age != 0 && got != cas.age {
+			t.Errorf("On case %v at %v, got age %v (expected %v)", cas.in, cas.now, got, cas.age)
+		}
+		if todo.Created.IsZero() && todo.Age() != 0 {
+			t.Errorf("On case %v, got age %v without a creation date", cas.in, todo.Age())
+		}
+		restore()
+	}
+}
+
+func TestDueWithin(t *testing.T) {
+	in := "a 2014-3-14\nb 2014-3-15\nc 2014-3-18\nd 2014-3-19\nx e 2014-3-16\nf\n"
+	l, err := FromReader(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+
+	cases := []struct {
+		now    time.Time
+		d      time.Duration
+		expect string
+	}{
+		{time.Date(2014, 3, 15, 0, 0, 0, 0, time.Local), 3 * 24 * time.Hour, "bc"},
+		{time.Date(2014, 3, 15, 0, 0, 0, 0, time.Local), 3*24*time.Hour - time.Nanosecond, "b"},
+		{time.Date(2014, 3, 15, 12, 0, 0, 0, time.Local), 3 * 24 * time.Hour, "bc"},
+		{time.Date(2014, 3, 15, 12, 0, 0, 0, time.Local), 0, "b"},
+		{time.Date(2014, 3, 15, 12, 0, 0, 0, time.Local), -time.Hour, ""},
+	}
+
+	for _, cas := range cases {
+		restore := setNow(cas.now)
+		var got string
+		for _, task := range l.DueWithin(cas.d) {
+			got += task.Title
+		}
+		restore()
+		if got != cas.expect {
+			t.Errorf("At %v within %v, got %v (expected %v)", cas.now, cas.d, got, cas.expect)
+		}
+	}
+}
+
+func TestDueBuckets(t *testing.T) {
+	in := "a 2014-3-11\nb 2014-3-12\nc 2014-3-13\nd 2014-3-16\ne 2014-3-17\nf\nx g 2014-3-11\n"
+	l, err := FromReader(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+
+	cases := []struct {
+		now    time.Time
+		expect map[string]string
+	}{
+		{
+			time.Date(2014, 3, 12, 9, 0, 0, 0, time.Local), // a Wednesday
+			map[string]string{"overdue": "a", "today": "b", "thisweek": "cd", "later": "e", "none": "f", "done": "g"},
+		},
+		{
+			time.Date(2014, 3, 16, 9, 0, 0, 0, time.Local), // a Sunday
+			map[string]string{"overdue": "abc", "today": "d", "later": "e", "none": "f", "done": "g"},
+		},
+		{
+			time.Date(2014, 3, 10, 0, 0, 0, 0, time.Local), // a Monday
+			map[string]string{"thisweek": "abcd", "later": "e", "none": "f", "done": "g"},
+		},
+	}
+
+	for _, cas := range cases {
+		restore := setNow(cas.now)
+		buckets := l.DueBuckets()
+		restore()
+		got := make(map[string]string)
+		for k, tasks := range buckets {
+			for _, task := range tasks {
+				got[k] += task.Title
+			}
+		}
+		if !reflect.DeepEqual(got, cas.expect) {
+			t.Errorf("At %v, got %v (expected %v)", cas.now, got, cas.expect)
+		}
+	}
+}
+
+func TestShift(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("no time zone database:", err)
+	}
+	defer func(old *time.Location) { time.Local = old }(time.Local)
+	time.Local = ny
+
+	cases := []struct {
+		in     string
+		d      time.Duration
+		expect string
+	}{
+		{"foo 2014-3-5 s:2014-3-1", 7 * 24 * time.Hour, "foo 2014-3-12 s:2014-3-8"},
+		{"foo 2014-3-8", 24 * time.Hour, "foo 2014-3-9"},   // spring forward
+		{"foo 2014-11-2", 24 * time.Hour, "foo 2014-11-3"}, // fall back
+		{"foo s:2014-11-3", -24 * time.Hour, "foo s:2014-11-2"},
+		{"foo", 24 * time.Hour, "foo"},
+		{"foo 2014-3-5", 36 * time.Hour, "foo 2014-3-6"},
+	}
+
+	for _, cas := range cases {
+		todo, err := Parse(cas.in)
+		if err != nil {
+			t.Fatalf("On case %v, unexpected parse error %v", cas.in, err)
+		}
+		todo.Shift(cas.d)
+		if got := todo.UnParse(); got != cas.expect {
+			t.Errorf("On case %v, got %v (expected %v)", cas.in, got, cas.expect)
+		}
+		if h := todo.Due.Hour(); cas.d%(24*time.Hour) == 0 && h != 0 {
+			t.Errorf("On case %v, got hour %v after shifting", cas.in, h)
+		}
+		if got := todo.UnParseOriginal(); got != cas.expect {
+			t.Errorf("On case %v, got original %v (expected %v)", cas.in, got, cas.expect)
+		}
+	}
+
+	l, err := FromReader(strings.NewReader("a 2014-3-5\nb s:2014-3-1\nc\n"))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+	l.Shift(48 * time.Hour)
+	var got []string
+	for _, todo := range l {
+		got = append(got, todo.UnParse())
+	}
+	if expect := []string{"a 2014-3-7", "b s:2014-3-3", "c"}; !equalStrings(got, expect) {
+		t.Errorf("Got %v (expected %v)", got, expect)
+	}
+}
+
+func TestWeekDate(t *testing.T) {
+	cases := []struct {
+		now    time.Time
+		in     string
+		expect string
+	}{
+		{time.Date(2014, 3, 5, 12, 0, 0, 0, time.Local), "foo W1", "foo 2013-12-30"},
+		{time.Date(2014, 3, 5, 12, 0, 0, 0, time.Local), "foo due:W12", "foo 2014-3-17"},
+		{time.Date(2014, 3, 5, 12, 0, 0, 0, time.Local), "foo W52", "foo 2014-12-22"},
+		{time.Date(2014, 3, 5, 12, 0, 0, 0, time.Local), "foo W53", "foo W53"},
+		{time.Date(2014, 3, 5, 12, 0, 0, 0, time.Local), `foo \W12`, `foo \W12`},
+		{time.Date(2015, 6, 1, 12, 0, 0, 0, time.Local), "foo W53", "foo 2015-12-28"},
+		{time.Date(2015, 6, 1, 12, 0, 0, 0, time.Local), "foo due:W01", "foo 2014-12-29"},
+		{time.Date(2015, 6, 1, 12, 0, 0, 0, time.Local), "foo W0 W123 w5", `foo W0 W123 w5`},
+		{time.Date(2014, 3, 5, 12, 0, 0, 0, time.Local), "x W12", "x due:2014-3-17"},
+		{time.Date(2014, 3, 5, 12, 0, 0, 0, time.Local), "x due:W3", "x due:2014-1-13"},
+		{time.Date(2014, 3, 5, 12, 0, 0, 0, time.Local), "x 2014-1-2 W12", "x 2014-1-2 due:2014-3-17"},
+		{time.Date(2014, 3, 5, 12, 0, 0, 0, time.Local), "x due:W3 pri:c", "x pri:c 2014-1-13"},
+	}
+
+	for _, cas := range cases {
+		restore := setNow(cas.now)
+		todo, err := Parse(cas.in)
+		got := todo.UnParse()
+		again, aerr := Parse(got)
+		restore()
+		if err != nil {
+			t.Errorf("On case %v, unexpected parse error %v", cas.in, err)
+			continue
+		}
+		if got != cas.expect {
+			t.Errorf("On case %v, got %v (expected %v)", cas.in, got, cas.expect)
+		}
+		if aerr != nil || !again.Due.Equal(todo.Due) || !again.Completed.Equal(todo.Completed) || !reflect.DeepEqual(again.Meta, todo.Meta) {
+			t.Errorf("On case %v, %v reparsed to %v, %v", cas.in, got, again, aerr)
+		}
+	}
+}
+
+func TestByDueDate(t *testing.T) {
+	l, err := FromReader(strings.NewReader(`b 2014-3-6
+none
+x done 2014-3-5
+(A) a 2014-3-6
+c 2014-3-1
+also none
+`))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+
+	groups := l.ByDueDate()
+	expect := []struct {
+		date   string
+		titles []string
+	}{
+		{"2014-3-1", []string{"c"}},
+		{"2014-3-5", []string{"done"}},
+		{"2014-3-6", []string{"a", "b"}},
+		{"", []string{"also none", "none"}},
+	}
+	if len(groups) != len(expect) {
+		t.Fatalf("Got %v groups (expected %v)", len(groups), len(expect))
+	}
+	for i, e := range expect {
+		var titles []string
+		for _, task := range groups[i].Tasks {
+			titles = append(titles, task.Title)
+		}
+		if got := formatDate(groups[i].Date); got != e.date || !equalStrings(titles, e.titles) {
+			t.Errorf("On group %v, got %v %v (expected %v %v)", i, got, titles, e.date, e.titles)
+		}
+	}
+
+	if groups := TaskList(nil).ByDueDate(); len(groups) != 0 {
+		t.Errorf("Got %v for an empty list", groups)
+	}
+}
+
+func TestStats(t *testing.T) {
+	defer setNow(time.Date(2014, 3, 5, 12, 0, 0, 0, time.Local))()
+
+	l, err := FromReader(strings.NewReader("a 2014-3-1\nb 2014-3-5\nx c 2014-3-1\nd\n"))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+	if got, expect := l.Stats(), (Stats{Total: 4, Done: 1, Pending: 3, Overdue: 1}); got != expect {
+		t.Errorf("Got %+v (expected %+v)", got, expect)
+	}
+	if got := l.CompletionRate(); got != 0.25 {
+		t.Errorf("Got rate %v (expected 0.25)", got)
+	}
+
+	var empty TaskList
+	if got := empty.CompletionRate(); got != 0 {
+		t.Errorf("Got rate %v for an empty list", got)
+	}
+	if got := empty.Stats(); got != (Stats{}) {
+		t.Errorf("Got %+v for an empty list", got)
+	}
+}
+
+func TestActiveOn(t *testing.T) {
+	l, err := FromReader(strings.NewReader(`start s:2014-3-5
+due 2014-3-5
+both s:2014-3-3 2014-3-7
+neither
+x done s:2014-3-1
+`))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+
+	cases := []struct {
+		day    time.Time
+		expect []string
+	}{
+		{time.Date(2014, 3, 1, 9, 0, 0, 0, time.Local), []string{"due", "neither"}},
+		{time.Date(2014, 3, 3, 0, 0, 0, 0, time.Local), []string{"due", "both", "neither"}},
+		{time.Date(2014, 3, 5, 23, 0, 0, 0, time.Local), []string{"start", "due", "both", "neither"}},
+		{time.Date(2014, 3, 7, 12, 0, 0, 0, time.Local), []string{"start", "both", "neither"}},
+		{time.Date(2014, 3, 8, 0, 0, 0, 0, time.Local), []string{"start", "neither"}},
+	}
+
+	for _, cas := range cases {
+		var got []string
+		for _, task := range l.ActiveOn(cas.day) {
+			got = append(got, task.Title)
+		}
+		if !equalStrings(got, cas.expect) {
+			t.Errorf("On %v, got %v (expected %v)", cas.day, got, cas.expect)
+		}
+	}
+}
+
+func TestDaysUntilDue(t *testing.T) {
+	cases := []struct {
+		now    time.Time
+		due    time.Time
+		expect int
+	}{
+		{time.Date(2014, 3, 5, 23, 0, 0, 0, time.Local), time.Date(2014, 3, 6, 0, 0, 0, 0, time.Local), 1},
+		{time.Date(2014, 3, 5, 1, 0, 0, 0, time.Local), time.Date(2014, 3, 6, 23, 0, 0, 0, time.Local), 1},
+		{time.Date(2014, 3, 5, 12, 0, 0, 0, time.Local), time.Date(2014, 3, 5, 0, 0, 0, 0, time.Local), 0},
+		{time.Date(2014, 1, 30, 12, 0, 0, 0, time.Local), time.Date(2014, 2, 2, 0, 0, 0, 0, time.Local), 3},
+		{time.Date(2014, 3, 2, 12, 0, 0, 0, time.Local), time.Date(2014, 2, 27, 0, 0, 0, 0, time.Local), -3},
+		{time.Date(2014, 12, 31, 12, 0, 0, 0, time.Local), time.Date(2015, 1, 1, 0, 0, 0, 0, time.Local), 1},
+	}
+
+	for _, cas := range cases {
+		restore := setNow(cas.now)
+		got, ok := Task{Title: "foo", Due: cas.due}.DaysUntilDue()
+		restore()
+		if !ok || got != cas.expect {
+			t.Errorf("At %v due %v, got %v, %v (expected %v)", cas.now, cas.due, got, ok, cas.expect)
+		}
+	}
+
+	if _, ok := (Task{Title: "foo"}).DaysUntilDue(); ok {
+		t.Errorf("Expected false with no due date")
+	}
+}
+
+func TestDateTime(t *testing.T) {
+	cases := []struct {
+		in     string
+		due    time.Time
+		start  time.Time
+		expect string
+	}{
+		{
+			"foo due:2024-01-02T15:00Z",
+			time.Date(2024, 1, 2, 15, 0, 0, 0, time.UTC), time.Time{},
+			"foo due:2024-01-02T15:00Z",
+		},
+		{
+			"foo s:2024-01-02T15:04:05+02:00 2024-1-5",
+			time.Date(2024, 1, 5, 0, 0, 0, 0, time.Local), time.Date(2024, 1, 2, 13, 4, 5, 0, time.UTC),
+			"foo 2024-1-5 s:2024-01-02T15:04:05+02:00",
+		},
+		{
+			"foo s:2024-01-02T15:00Z s:2024-1-3",
+			time.Time{}, time.Date(2024, 1, 3, 0, 0, 0, 0, time.Local),
+			"foo s:2024-1-3",
+		},
+		{
+			`foo \due:2024-01-02T15:00Z \s:2024-01-02T15:00Z`,
+			time.Time{}, time.Time{},
+			`foo \due:2024-01-02T15:00Z \s:2024-01-02T15:00Z`,
+		},
+		{"foo due:2024-01-02T25:00Z", time.Time{}, time.Time{}, "foo due:2024-01-02T25:00Z"},
+	}
+
+	for _, cas := range cases {
+		todo, err := Parse(cas.in)
+		if err != nil {
+			t.Errorf("On case %v, unexpected parse error %v", cas.in, err)
+			continue
+		}
+		if !todo.Due.Equal(cas.due) || !todo.Start.Equal(cas.start) {
+			t.Errorf("On case %v, got due %v start %v (expected %v %v)", cas.in, todo.Due, todo.Start, cas.due, cas.start)
+		}
+		got := todo.UnParse()
+		if got != cas.expect {
+			t.Errorf("On case %v, got %v (expected %v)", cas.in, got, cas.expect)
+		}
+		if again, err := Parse(got); err != nil || again.UnParse() != got || !again.Due.Equal(todo.Due) {
+			t.Errorf("On case %v, reparsed to %v, %v", cas.in, again.UnParse(), err)
+		}
+	}
+
+	if _, err := Parse("foo due:2024-01-02T15:00Z 2024-1-3"); err == nil {
+		t.Errorf("Expected an error for two due dates")
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2014 Ethan Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package todo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// FromFile reads the file at path with FromReader. Parse errors are
+// prefixed with the path; errors opening the file already name it.
+func FromFile(path string) (TaskList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	ts, err := FromReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return ts, nil
+}
+
+// FromDir reads every .txt file in dir with FromFile, in order of
+// name, and returns all their tasks. Each task's Source is set to the
+// name of its file.
+func FromDir(dir string) (TaskList, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.txt"))
+	if err != nil {
+		return nil, err
+	}
+	var ret TaskList
+	for _, path := range paths {
+		ts, err := FromFile(path)
+		if err != nil {
+			return nil, err
+		}
+		for i := range ts {
+			ts[i].Source = filepath.Base(path)
+		}
+		ret = append(ret, ts...)
+	}
+	return ret, nil
+}
+
+// ToFile writes the list to the file at path with ToWriter. It writes
+// to a temporary file in the same directory and renames it into place,
+// so the file at path is never left partly written. An existing file's
+// permissions are kept.
+func (l TaskList) ToFile(path string) error {
+	mode := os.FileMode(0644)
+	if fi, err := os.Stat(path); err == nil {
+		mode = fi.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // fails harmlessly once renamed
+
+	if err := l.ToWriter(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
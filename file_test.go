@@ -0,0 +1,103 @@
+// Copyright 2014 Ethan Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package todo
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "todo.txt")
+
+	l, err := FromReader(strings.NewReader("(A) call mom @phone\nx buy milk\n"))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+	if err := l.ToFile(path); err != nil {
+		t.Fatalf("unexpected write error %v", err)
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := l[:1].ToFile(path); err != nil {
+		t.Fatalf("unexpected write error %v", err)
+	}
+
+	got, err := FromFile(path)
+	if err != nil {
+		t.Fatalf("unexpected read error %v", err)
+	}
+	if len(got) != 1 || got[0].UnParse() != "(A) call mom @phone" {
+		t.Errorf("Got %v, expected the first task", got)
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("Got mode %v, %v, expected 0600 to be kept", fi.Mode(), err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("Got %v files, expected temporary files to be removed", len(entries))
+	}
+
+	if _, err := FromFile(filepath.Join(dir, "missing.txt")); !os.IsNotExist(err) {
+		t.Errorf("Got %v, expected a not exist error", err)
+	}
+
+	bad := filepath.Join(dir, "bad.txt")
+	if err := os.WriteFile(bad, []byte("ok\nx\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = FromFile(bad)
+	var perr *ParseError
+	if err == nil || !strings.HasPrefix(err.Error(), bad+": ") || !errors.As(err, &perr) || perr.Line != 2 {
+		t.Errorf("Got %v, expected a parse error on line 2 of %v", err, bad)
+	}
+}
+
+func TestFromDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"work.txt": "(A) write report +work\nx file expenses\n",
+		"home.txt": "buy milk @store\n",
+		"notes.md": "not a todo file\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	l, err := FromDir(dir)
+	if err != nil {
+		t.Fatalf("unexpected read error %v", err)
+	}
+	expect := []struct{ title, source string }{
+		{"buy milk", "home.txt"},
+		{"write report", "work.txt"},
+		{"file expenses", "work.txt"},
+	}
+	if len(l) != len(expect) {
+		t.Fatalf("Got %v tasks (expected %v)", len(l), len(expect))
+	}
+	for i, e := range expect {
+		if l[i].Title != e.title || l[i].Source != e.source {
+			t.Errorf("Got %v from %v (expected %v from %v)", l[i].Title, l[i].Source, e.title, e.source)
+		}
+	}
+
+	bad := filepath.Join(dir, "bad.txt")
+	if err := os.WriteFile(bad, []byte("fine\nx\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = FromDir(dir)
+	var perr *ParseError
+	if !errors.As(err, &perr) || !strings.HasPrefix(err.Error(), bad+": ") {
+		t.Errorf("Got %v, expected a parse error naming %v", err, bad)
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2014 Ethan Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package todo
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// icalDate is the iCalendar DATE value format.
+const icalDate = "20060102"
+
+var icalEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\n", `\n`,
+)
+
+// ToICal writes the list to w as an iCalendar (RFC 5545) VCALENDAR
+// with one VTODO per task. Due and start dates are written as DATE values,
+// and tags and contexts are written as categories.
+func (ts TaskList) ToICal(w io.Writer) error {
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//millere//todo//EN",
+	}
+	stamp := Now().UTC().Format("20060102T150405Z")
+	for i, t := range ts {
+		lines = append(lines,
+			"BEGIN:VTODO",
+			fmt.Sprintf("UID:%x-%d@todo", sha1.Sum([]byte(t.UnParse())), i),
+			"DTSTAMP:"+stamp,
+			"SUMMARY:"+icalEscaper.Replace(t.Title),
+		)
+		if !t.Start.IsZero() {
+			lines = append(lines, "DTSTART;VALUE=DATE:"+t.Start.Format(icalDate))
+		}
+		if !t.Due.IsZero() {
+			lines = append(lines, "DUE;VALUE=DATE:"+t.Due.Format(icalDate))
+		}
+		if t.Done {
+			lines = append(lines, "STATUS:COMPLETED")
+		} else {
+			lines = append(lines, "STATUS:NEEDS-ACTION")
+		}
+		var categories []string
+		for _, c := range append(append([]string(nil), t.Tags...), t.Contexts...) {
+			categories = append(categories, icalEscaper.Replace(c))
+		}
+		if len(categories) > 0 {
+			lines = append(lines, "CATEGORIES:"+strings.Join(categories, ","))
+		}
+		lines = append(lines, "END:VTODO")
+	}
+	lines = append(lines, "END:VCALENDAR")
+
+	for _, line := range lines {
+		if _, err := io.WriteString(w, icalFold(line)+"\r\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// icalFold folds a content line so that no line is longer than
+// 75 octets, without splitting a UTF-8 sequence.
+func icalFold(line string) string {
+	var b strings.Builder
+	limit := 75
+	for len(line) > limit {
+		i := limit
+		for i > 0 && !utf8.RuneStart(line[i]) {
+			i--
+		}
+		b.WriteString(line[:i])
+		b.WriteString("\r\n ")
+		line = line[i:]
+		limit = 74 // leave room for the leading space
+	}
+	b.WriteString(line)
+	return b.String()
+}
@@ -0,0 +1,80 @@
+// Copyright 2014 Ethan Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package todo
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestToICal(t *testing.T) {
+	defer setNow(time.Date(2014, 12, 1, 0, 0, 0, 0, time.UTC))()
+
+	l, err := FromReader(strings.NewReader("feed cats, dogs 2014-12-23 s:2014-12-20 @home +pets\nx eat lunch\n"))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+	var buf bytes.Buffer
+	if err := l.ToICal(&buf); err != nil {
+		t.Fatalf("unexpected write error %v", err)
+	}
+
+	out := buf.String()
+	if !strings.HasPrefix(out, "BEGIN:VCALENDAR\r\n") || !strings.HasSuffix(out, "END:VCALENDAR\r\n") {
+		t.Errorf("Got %q, expected a VCALENDAR", out)
+	}
+
+	var todos []map[string]string
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n") {
+		i := strings.Index(line, ":")
+		if i < 0 {
+			t.Fatalf("Got malformed line %q", line)
+		}
+		switch {
+		case line == "BEGIN:VTODO":
+			todos = append(todos, make(map[string]string))
+		case len(todos) > 0:
+			todos[len(todos)-1][line[:i]] = line[i+1:]
+		}
+	}
+	if len(todos) != 2 {
+		t.Fatalf("Got %v VTODOs, expected 2", len(todos))
+	}
+
+	expect := map[string]string{
+		"SUMMARY":            `feed cats\, dogs`,
+		"DUE;VALUE=DATE":     "20141223",
+		"DTSTART;VALUE=DATE": "20141220",
+		"STATUS":             "NEEDS-ACTION",
+		"CATEGORIES":         "pets,home",
+		"DTSTAMP":            "20141201T000000Z",
+	}
+	for k, v := range expect {
+		if todos[0][k] != v {
+			t.Errorf("Got %v %q, expected %q", k, todos[0][k], v)
+		}
+	}
+	if todos[1]["STATUS"] != "COMPLETED" {
+		t.Errorf("Got status %q, expected COMPLETED", todos[1]["STATUS"])
+	}
+	if _, ok := todos[1]["DUE;VALUE=DATE"]; ok {
+		t.Errorf("Got a due date for a task without one")
+	}
+}
+
+func TestICalFold(t *testing.T) {
+	line := "SUMMARY:" + strings.Repeat("é", 100)
+	folded := icalFold(line)
+	for _, l := range strings.Split(folded, "\r\n") {
+		if len(l) > 75 {
+			t.Errorf("Got line of %v octets", len(l))
+		}
+	}
+	if got := strings.Replace(folded, "\r\n ", "", -1); got != line {
+		t.Errorf("Unfolded to %q, expected %q", got, line)
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2014 Ethan Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package todo
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// jsonTask is the wire representation of a Task.
+// Dates are formatted with DateFormat, or empty when unset. Due and
+// start dates that were written with a time keep it, in RFC 3339 form.
+type jsonTask struct {
+	Title      string            `json:"title"`
+	Priority   string            `json:"priority"`
+	Start      string            `json:"start"`
+	Due        string            `json:"due"`
+	Completed  string            `json:"completed"`
+	Created    string            `json:"created"`
+	Recurrence string            `json:"recurrence"`
+	Tags       []string          `json:"tags"`
+	Contexts   []string          `json:"contexts"`
+	Meta       map[string]string `json:"meta"`
+	Notes      []string          `json:"notes"`
+	Raw        string            `json:"raw"`
+	Done       bool              `json:"done"`
+	Flagged    bool              `json:"flagged"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (t Task) MarshalJSON() ([]byte, error) {
+	j := jsonTask{
+		Title:      t.Title,
+		Start:      formatTimed(t.Start, t.startTime),
+		Due:        formatTimed(t.Due, t.dueTime),
+		Completed:  formatDate(t.Completed),
+		Created:    formatDate(t.Created),
+		Recurrence: t.Recurrence.String(),
+		Tags:       t.Tags,
+		Contexts:   t.Contexts,
+		Meta:       t.Meta,
+		Notes:      t.Notes,
+		Raw:        t.Raw,
+		Done:       t.Done,
+		Flagged:    t.Flagged,
+	}
+	if t.Priority != 0 {
+		j.Priority = string(t.Priority)
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (t *Task) UnmarshalJSON(data []byte) error {
+	var j jsonTask
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	var n Task
+	n.Title = j.Title
+	n.Tags = j.Tags
+	n.Contexts = j.Contexts
+	n.Meta = j.Meta
+	n.Notes = j.Notes
+	n.Raw = j.Raw
+	n.Done = j.Done
+	n.Flagged = j.Flagged
+
+	if len(j.Priority) > 0 {
+		p, ok := parsePriority("(" + j.Priority + ")")
+		if !ok {
+			return fmt.Errorf("todo: invalid priority %q", j.Priority)
+		}
+		n.Priority = p
+	}
+
+	var err error
+	if n.Start, n.startTime, err = parseTimed(j.Start); err != nil {
+		return err
+	}
+	if n.Due, n.dueTime, err = parseTimed(j.Due); err != nil {
+		return err
+	}
+	if n.Completed, err = parseDate(j.Completed); err != nil {
+		return err
+	}
+	if n.Created, err = parseDate(j.Created); err != nil {
+		return err
+	}
+
+	if len(j.Recurrence) > 0 {
+		if n.Recurrence, err = parseRecurrence(j.Recurrence); err != nil {
+			return err
+		}
+	}
+
+	*t = n
+	return nil
+}
+
+func formatDate(d time.Time) string {
+	if d.IsZero() {
+		return ""
+	}
+	return d.Format(DateFormat)
+}
+
+func parseDate(s string) (time.Time, error) {
+	if len(s) == 0 {
+		return time.Time{}, nil
+	}
+	return time.ParseInLocation(DateFormat, s, time.Local)
+}
+
+// formatTimed formats d with formatDateTime when timed is set and with
+// formatDate otherwise.
+func formatTimed(d time.Time, timed bool) string {
+	if timed && !d.IsZero() {
+		return formatDateTime(d)
+	}
+	return formatDate(d)
+}
+
+// parseTimed parses s as a date, or failing that as a date and time.
+// The returned bool reports whether s included a time.
+func parseTimed(s string) (time.Time, bool, error) {
+	d, err := parseDate(s)
+	if err == nil {
+		return d, false, nil
+	}
+	if d, terr := dateTime(s); terr == nil {
+		return d, true, nil
+	}
+	return time.Time{}, false, err
+}
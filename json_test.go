@@ -0,0 +1,73 @@
+// Copyright 2014 Ethan Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package todo
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJSON(t *testing.T) {
+	cases := []string{
+		"Hello",
+		"(A) call mom 2014-12-23 s:2014-12-20 @phone +family +weekly rec:1w",
+		"x 2014-1-2 2013-12-30 buy milk @store",
+		"call mom due:2024-01-02T15:00Z s:2024-01-02T09:30:15+02:00",
+	}
+
+	for _, in := range cases {
+		task, err := Parse(in)
+		if err != nil {
+			t.Fatalf("On case %v, unexpected parse error %v", in, err)
+		}
+		task.index = 0
+		task.original = ""
+		task.order = nil
+
+		data, err := json.Marshal(task)
+		if err != nil {
+			t.Errorf("On case %v, unexpected marshal error %v", in, err)
+			continue
+		}
+		var out Task
+		if err := json.Unmarshal(data, &out); err != nil {
+			t.Errorf("On case %v, unexpected unmarshal error %v", in, err)
+			continue
+		}
+		if !reflect.DeepEqual(out, task) {
+			t.Errorf("On case %v, got %#v (expected %#v)", in, out, task)
+		}
+		if got, expect := out.UnParse(), task.UnParse(); got != expect {
+			t.Errorf("On case %v, got %v (expected %v)", in, got, expect)
+		}
+	}
+}
+
+func TestJSONList(t *testing.T) {
+	l, err := FromReader(strings.NewReader("a 2014-1-2\nb\n"))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+	data, err := json.Marshal(l)
+	if err != nil {
+		t.Fatalf("unexpected marshal error %v", err)
+	}
+	if !strings.HasPrefix(string(data), "[{") || !strings.Contains(string(data), `"due":"2014-1-2"`) {
+		t.Errorf("Got %s", data)
+	}
+	if strings.Contains(string(data), "index") || strings.Contains(string(data), "original") {
+		t.Errorf("Got unexported fields in %s", data)
+	}
+
+	var out TaskList
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatalf("unexpected unmarshal error %v", err)
+	}
+	if len(out) != 2 || out[0].Title != "a" || out[1].Title != "b" {
+		t.Errorf("Got %v", out)
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright 2014 Ethan Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package todo
+
+import (
+	"strings"
+	"time"
+)
+
+// A Query selects tasks by several fields at once. A task matches
+// only if it meets every criterion that is set; zero fields match
+// any task.
+type Query struct {
+	TitleContains string    // substring of the title
+	Tags          []string  // tags the task must all have
+	Contexts      []string  // contexts the task must all have
+	Done          *bool     // done status, or nil for either
+	DueBefore     time.Time // the task must be due strictly before this
+}
+
+// Match reports whether t meets every criterion of the query.
+func (q Query) Match(t Task) bool {
+	if !strings.Contains(t.Title, q.TitleContains) {
+		return false
+	}
+	for _, tag := range q.Tags {
+		if !elementof(tag, t.Tags) {
+			return false
+		}
+	}
+	for _, context := range q.Contexts {
+		if !elementof(context, t.Contexts) {
+			return false
+		}
+	}
+	if q.Done != nil && *q.Done != t.Done {
+		return false
+	}
+	if !q.DueBefore.IsZero() && (t.Due.IsZero() || !t.Due.Before(q.DueBefore)) {
+		return false
+	}
+	return true
+}
+
+// Search returns a new tasklist containing all of the tasks that
+// match the query.
+func (ts TaskList) Search(q Query) TaskList {
+	return ts.FilterFunc(q.Match)
+}
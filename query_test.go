@@ -0,0 +1,49 @@
+// Copyright 2014 Ethan Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package todo
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSearch(t *testing.T) {
+	in := "buy milk @store +food 2014-3-1\n" +
+		"x buy eggs @store +food 2014-3-5\n" +
+		"call mom @phone +family\n" +
+		"buy gift @store +family 2014-3-10\n"
+	l, err := FromReader(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+
+	yes, no := true, false
+	cases := []struct {
+		q      Query
+		expect string
+	}{
+		{Query{}, "buy milk|buy eggs|call mom|buy gift"},
+		{Query{TitleContains: "buy"}, "buy milk|buy eggs|buy gift"},
+		{Query{TitleContains: "buy", Done: &no}, "buy milk|buy gift"},
+		{Query{Done: &yes}, "buy eggs"},
+		{Query{Tags: []string{"family"}}, "call mom|buy gift"},
+		{Query{Tags: []string{"family"}, Contexts: []string{"store"}}, "buy gift"},
+		{Query{Tags: []string{"family", "food"}}, ""},
+		{Query{DueBefore: time.Date(2014, 3, 5, 0, 0, 0, 0, time.Local)}, "buy milk"},
+		{Query{DueBefore: time.Date(2014, 3, 6, 0, 0, 0, 0, time.Local), Done: &no}, "buy milk"},
+		{Query{TitleContains: "buy", Contexts: []string{"store"}, DueBefore: time.Date(2015, 1, 1, 0, 0, 0, 0, time.Local)}, "buy milk|buy eggs|buy gift"},
+	}
+
+	for _, cas := range cases {
+		var titles []string
+		for _, task := range l.Search(cas.q) {
+			titles = append(titles, task.Title)
+		}
+		if got := strings.Join(titles, "|"); got != cas.expect {
+			t.Errorf("On query %+v, got %v (expected %v)", cas.q, got, cas.expect)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2014 Ethan Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package todo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A Recurrence is the interval at which a task repeats, as written
+// in a rec: token. The zero value means the task does not recur.
+type Recurrence struct {
+	Count int
+	Unit  byte // 'd', 'w', 'm', or 'y'
+
+	// FromCompletion, written as a + before the count, makes the next
+	// occurrence due an interval after the task was completed, rather
+	// than after it was due.
+	FromCompletion bool
+}
+
+// IsZero reports whether r is the zero Recurrence.
+func (r Recurrence) IsZero() bool {
+	return r == Recurrence{}
+}
+
+// String returns the recurrence in rec: form, without the prefix.
+func (r Recurrence) String() string {
+	if r.IsZero() {
+		return ""
+	}
+	s := strconv.Itoa(r.Count) + string(r.Unit)
+	if r.FromCompletion {
+		s = "+" + s
+	}
+	return s
+}
+
+func parseRecurrence(s string) (Recurrence, error) {
+	fromCompletion := false
+	if strings.HasPrefix(s, "+") {
+		fromCompletion = true
+		s = s[1:]
+	}
+	if len(s) < 2 {
+		return Recurrence{}, fmt.Errorf("todo: bad recurrence %q", s)
+	}
+	unit := s[len(s)-1]
+	switch unit {
+	case 'd', 'w', 'm', 'y':
+	default:
+		return Recurrence{}, fmt.Errorf("todo: bad recurrence unit in %q", s)
+	}
+	count, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || count <= 0 || s[0] == '+' {
+		return Recurrence{}, fmt.Errorf("todo: bad recurrence count in %q", s)
+	}
+	return Recurrence{Count: count, Unit: unit, FromCompletion: fromCompletion}, nil
+}
+
+// Add returns d advanced by the recurrence interval. Months and years
+// that overflow the target month are clamped to its last day, so
+// Jan 31 plus one month is the last day of February.
+func (r Recurrence) Add(d time.Time) time.Time {
+	switch r.Unit {
+	case 'd':
+		return d.AddDate(0, 0, r.Count)
+	case 'w':
+		return d.AddDate(0, 0, 7*r.Count)
+	case 'm':
+		return addMonths(d, r.Count)
+	case 'y':
+		return addMonths(d, 12*r.Count)
+	}
+	return d
+}
+
+func addMonths(d time.Time, n int) time.Time {
+	y, m, day := d.Date()
+	first := time.Date(y, m+time.Month(n), 1, d.Hour(), d.Minute(), d.Second(), d.Nanosecond(), d.Location())
+	last := first.AddDate(0, 1, -1).Day()
+	if day > last {
+		day = last
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+// Next returns the next occurrence of a recurring task: a copy that
+// is not done, with its due and start dates advanced by the
+// recurrence interval. It returns false if the task does not recur.
+//
+// If the recurrence is FromCompletion, the due date is instead the
+// interval after the completion date, or after today if there is
+// none, and the start date keeps its distance from the due date.
+func (t Task) Next() (Task, bool) {
+	if t.Recurrence.IsZero() {
+		return Task{}, false
+	}
+	n := t.Clone()
+	n.Done = false
+	n.Completed = time.Time{}
+	if t.Recurrence.FromCompletion {
+		base := t.Completed
+		if base.IsZero() {
+			base = today()
+		}
+		switch {
+		case !t.Due.IsZero():
+			n.Due = t.Recurrence.Add(base)
+			if !t.Start.IsZero() {
+				n.Start = n.Due.AddDate(0, 0, -daysBetween(t.Start, t.Due))
+			}
+		case !t.Start.IsZero():
+			n.Start = t.Recurrence.Add(base)
+		}
+	} else {
+		if !n.Due.IsZero() {
+			n.Due = t.Recurrence.Add(n.Due)
+		}
+		if !n.Start.IsZero() {
+			n.Start = t.Recurrence.Add(n.Start)
+		}
+	}
+	n.Raw = n.UnParse()
+	n.original = ""
+	n.dirty = false
+	n.index = 0
+	return n, true
+}
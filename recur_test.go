@@ -0,0 +1,90 @@
+// Copyright 2014 Ethan Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package todo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecurrenceParse(t *testing.T) {
+	cases := []struct {
+		in    string
+		rec   Recurrence
+		title string
+	}{
+		{"chore rec:1w", Recurrence{1, 'w', false}, "chore"},
+		{"chore rec:3d", Recurrence{3, 'd', false}, "chore"},
+		{"chore rec:12m", Recurrence{12, 'm', false}, "chore"},
+		{"chore rec:2y", Recurrence{2, 'y', false}, "chore"},
+		{"chore rec:1x", Recurrence{}, "chore rec:1x"},
+		{"chore rec:w", Recurrence{}, "chore rec:w"},
+		{"chore rec:0d", Recurrence{}, "chore rec:0d"},
+		{"chore rec:+2w", Recurrence{2, 'w', true}, "chore"},
+		{"chore rec:++2w", Recurrence{}, "chore rec:++2w"},
+		{"chore rec:+-2w", Recurrence{}, "chore rec:+-2w"},
+	}
+
+	for _, cas := range cases {
+		todo, err := Parse(cas.in)
+		if err != nil {
+			t.Errorf("On case %v, unexpected parse error %v", cas.in, err)
+			continue
+		}
+		if todo.Recurrence != cas.rec {
+			t.Errorf("On case %v, got %v (expected %v)", cas.in, todo.Recurrence, cas.rec)
+		}
+		if todo.Title != cas.title {
+			t.Errorf("On case %v, got title %v (expected %v)", cas.in, todo.Title, cas.title)
+		}
+		if got := todo.UnParse(); got != cas.in {
+			t.Errorf("On case %v, unparsed to %v", cas.in, got)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	cases := []struct {
+		in     string
+		expect string
+	}{
+		{"x 2015-1-2 chore 2015-1-1 rec:3d", "chore 2015-1-4 rec:3d"},
+		{"x chore 2015-1-1 s:2014-12-31 rec:1w", "chore 2015-1-8 s:2015-1-7 rec:1w"},
+		{"x rent 2015-1-31 rec:1m", "rent 2015-2-28 rec:1m"},
+		{"x rent 2016-1-31 rec:1m", "rent 2016-2-29 rec:1m"},
+		{"x rent 2015-12-31 rec:2m", "rent 2016-2-29 rec:2m"},
+		{"x rent 2015-3-31 rec:1m", "rent 2015-4-30 rec:1m"},
+		{"x leap 2016-2-29 rec:1y", "leap 2017-2-28 rec:1y"},
+		{"x 2015-1-10 chore 2015-1-1 rec:1w", "chore 2015-1-8 rec:1w"},
+		{"x 2015-1-10 chore 2015-1-1 rec:+1w", "chore 2015-1-17 rec:+1w"},
+		{"x 2015-1-10 chore 2015-1-5 s:2015-1-3 rec:+1w", "chore 2015-1-17 s:2015-1-15 rec:+1w"},
+		{"x 2015-1-10 chore s:2015-1-3 rec:+3d", "chore s:2015-1-13 rec:+3d"},
+		{"x 2015-1-10 rent 2015-1-31 rec:+1m", "rent 2015-2-10 rec:+1m"},
+		{"x chore 2015-1-1 rec:+1d", "chore 2015-3-6 rec:+1d"},
+	}
+
+	defer setNow(time.Date(2015, 3, 5, 12, 0, 0, 0, time.Local))()
+
+	for _, cas := range cases {
+		todo, err := Parse(cas.in)
+		if err != nil {
+			t.Errorf("On case %v, unexpected parse error %v", cas.in, err)
+			continue
+		}
+		next, ok := todo.Next()
+		if !ok {
+			t.Errorf("On case %v, got no next task", cas.in)
+			continue
+		}
+		if got := next.UnParse(); got != cas.expect {
+			t.Errorf("On case %v, got %v (expected %v)", cas.in, got, cas.expect)
+		}
+	}
+
+	todo, _ := Parse("x once 2015-1-1")
+	if _, ok := todo.Next(); ok {
+		t.Errorf("Got next task for a non-recurring task")
+	}
+}
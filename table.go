@@ -0,0 +1,104 @@
+// Copyright 2014 Ethan Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package todo
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// TableTitleWidth is the widest, in runes, a title may be in Table.
+// Longer titles are truncated with an ellipsis. Zero means no limit.
+var TableTitleWidth = 40
+
+// Table formats the list as a table with a header row and one row per
+// task, padded with spaces so the columns line up in a terminal.
+func (ts TaskList) Table() string {
+	rows := [][]string{{"", "done", "title", "due", "start", "contexts", "tags"}}
+	for _, t := range ts {
+		done := ""
+		if t.Done {
+			done = "x"
+		}
+		rows = append(rows, []string{
+			strconv.Itoa(t.index),
+			done,
+			truncate(t.Title, TableTitleWidth),
+			formatDate(t.Due),
+			formatDate(t.Start),
+			strings.Join(t.Contexts, ", "),
+			strings.Join(t.Tags, ", "),
+		})
+	}
+
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, cell := range row {
+			if n := utf8.RuneCountInString(cell); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+
+	var b strings.Builder
+	for _, row := range rows {
+		var line string
+		for i, cell := range row {
+			if i > 0 {
+				line += "  "
+			}
+			line += cell + strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell))
+		}
+		b.WriteString(strings.TrimRight(line, " "))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// truncate shortens s to at most width runes, ending it with an
+// ellipsis if anything was cut. A width of zero means no limit.
+func truncate(s string, width int) string {
+	if width <= 0 || utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	r := []rune(s)
+	return string(r[:width-1]) + "…"
+}
+
+// WrappedTitle breaks the title into lines of at most width runes,
+// between words where possible. Words longer than width are split.
+// A width of zero or less returns the title on one line.
+func (t Task) WrappedTitle(width int) []string {
+	words := strings.Fields(t.Title)
+	if width <= 0 {
+		if len(words) == 0 {
+			return nil
+		}
+		return []string{strings.Join(words, " ")}
+	}
+
+	var lines []string
+	var line []rune
+	for _, w := range words {
+		word := []rune(w)
+		if len(line) > 0 && len(line)+1+len(word) <= width {
+			line = append(append(line, ' '), word...)
+			continue
+		}
+		if len(line) > 0 {
+			lines = append(lines, string(line))
+		}
+		for len(word) > width {
+			lines = append(lines, string(word[:width]))
+			word = word[width:]
+		}
+		line = word
+	}
+	if len(line) > 0 {
+		lines = append(lines, string(line))
+	}
+	return lines
+}
@@ -0,0 +1,62 @@
+// Copyright 2014 Ethan Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package todo
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTable(t *testing.T) {
+	in := "feed cats 2014-12-23 @home +pets\n" +
+		"x eat lunch\n" +
+		"write a very long novel about café life s:2015-1-1 @desk @café +art +books\n"
+	l, err := FromReader(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+
+	defer func(w int) { TableTitleWidth = w }(TableTitleWidth)
+	TableTitleWidth = 20
+
+	expect := "" +
+		"   done  title                 due         start     contexts    tags\n" +
+		"1        feed cats             2014-12-23            home        pets\n" +
+		"2  x     eat lunch\n" +
+		"3        write a very long n…              2015-1-1  desk, café  art, books\n"
+	if got := l.Table(); got != expect {
+		t.Errorf("Got\n%v\nexpected\n%v", got, expect)
+	}
+
+	TableTitleWidth = 0
+	if got := l.Table(); !strings.Contains(got, "write a very long novel about café life  ") {
+		t.Errorf("Got\n%v\nexpected the untruncated title", got)
+	}
+}
+
+func TestWrappedTitle(t *testing.T) {
+	cases := []struct {
+		title  string
+		width  int
+		expect []string
+	}{
+		{"", 10, nil},
+		{"buy milk", 10, []string{"buy milk"}},
+		{"buy milk and eggs", 8, []string{"buy milk", "and eggs"}},
+		{"buy  milk   and eggs", 9, []string{"buy milk", "and eggs"}},
+		{"café crème brûlée", 10, []string{"café crème", "brûlée"}},
+		{"a supercalifragilistic word", 6, []string{"a", "superc", "alifra", "gilist", "ic", "word"}},
+		{"ünïcödéwörd", 4, []string{"ünïc", "ödéw", "örd"}},
+		{"buy milk", 0, []string{"buy milk"}},
+	}
+
+	for _, cas := range cases {
+		got := Task{Title: cas.title}.WrappedTitle(cas.width)
+		if !reflect.DeepEqual(got, cas.expect) {
+			t.Errorf("On %q width %v, got %q (expected %q)", cas.title, cas.width, got, cas.expect)
+		}
+	}
+}
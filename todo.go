@@ -7,11 +7,16 @@ package todo
 
 import (
 	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
+	"sort"
 	"strings"
 	"time"
+	"unicode"
 )
 
 // A TaskList is a list of tasks
@@ -22,8 +27,10 @@ func (l TaskList) Swap(i, j int) { l[i], l[j] = l[j], l[i] }
 func (l TaskList) Less(i, j int) bool {
 	// sort by:
 	// not done before done
+	// then by priority
 	// then by due date
 	// then by start date
+	// then flagged before not flagged
 	// then alphabetically
 	if l[i].Done && !l[j].Done {
 		return false
@@ -32,6 +39,10 @@ func (l TaskList) Less(i, j int) bool {
 		return true
 	}
 
+	if l[i].Priority != l[j].Priority {
+		return higherPriority(l[i].Priority, l[j].Priority)
+	}
+
 	dbefore, eq := before(l[i].Due, l[j].Due)
 	if !eq {
 		return dbefore
@@ -42,65 +53,944 @@ func (l TaskList) Less(i, j int) bool {
 		return sbefore
 	}
 
-	return l[i].Title < l[j].Title
+	if l[i].Flagged != l[j].Flagged {
+		return l[i].Flagged
+	}
+
+	return l[i].Title < l[j].Title
+}
+
+// Sorted returns a sorted copy of the list, leaving the list itself
+// unchanged.
+func (l TaskList) Sorted() TaskList {
+	ret := append(TaskList(nil), l...)
+	sort.Sort(ret)
+	return ret
+}
+
+// SortBy returns a copy of the list sorted by less, leaving the list
+// itself unchanged. Tasks that compare equal keep their order.
+func (l TaskList) SortBy(less func(a, b Task) bool) TaskList {
+	ret := append(TaskList(nil), l...)
+	sort.SliceStable(ret, func(i, j int) bool { return less(ret[i], ret[j]) })
+	return ret
+}
+
+// Reverse reverses the order of the list in place.
+func (l TaskList) Reverse() {
+	for i, j := 0, len(l)-1; i < j; i, j = i+1, j-1 {
+		l[i], l[j] = l[j], l[i]
+	}
+}
+
+// Reversed returns a reversed copy of the list, leaving the list
+// itself unchanged.
+func (l TaskList) Reversed() TaskList {
+	ret := append(TaskList(nil), l...)
+	ret.Reverse()
+	return ret
+}
+
+// SortByDueDesc sorts the list in place with not done tasks first,
+// then by due date with the latest first and tasks without a due
+// date last, then alphabetically.
+func (l TaskList) SortByDueDesc() {
+	sort.Sort(byDueDesc{l})
+}
+
+type byDueDesc struct{ TaskList }
+
+func (l byDueDesc) Less(i, j int) bool {
+	a, b := l.TaskList[i], l.TaskList[j]
+	if a.Done != b.Done {
+		return !a.Done
+	}
+	if !a.Due.Equal(b.Due) {
+		if a.Due.IsZero() || b.Due.IsZero() {
+			return b.Due.IsZero()
+		}
+		return a.Due.After(b.Due)
+	}
+	return a.Title < b.Title
+}
+
+// higherPriority reports whether a sorts ahead of b.
+// Unset priorities sort after all set ones.
+func higherPriority(a, b byte) bool {
+	if a == 0 {
+		return false
+	}
+	if b == 0 {
+		return true
+	}
+	return a < b
+}
+
+// SortStable sorts the list in place like sort.Sort, but tasks
+// that compare equal keep their original order, so a file's
+// ordering is preserved among equal elements.
+func (l TaskList) SortStable() {
+	sort.Stable(l)
+}
+
+// returns before, equal
+func before(a, b time.Time) (bool, bool) {
+	if a.Equal(b) {
+		return false, true
+	}
+	if a.IsZero() && !b.IsZero() {
+		return false, false
+	}
+	if !a.IsZero() && b.IsZero() {
+		return true, false
+	}
+	if a.Before(b) {
+		return true, false
+	} else {
+		return false, false
+	}
+}
+
+// FromReader parses each line of r as a task, using the default Parser.
+func FromReader(r io.Reader) (TaskList, error) {
+	return Parser{}.FromReader(r)
+}
+
+// FromBytes parses each line of b as a task, like FromReader.
+func FromBytes(b []byte) (TaskList, error) {
+	return FromReader(bytes.NewReader(b))
+}
+
+// FromReader parses each line of r as a task.
+func (p Parser) FromReader(r io.Reader) (TaskList, error) {
+	var ret TaskList
+	err := p.EachTask(r, func(t Task) error {
+		ret = append(ret, t)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// EachTask parses r one line at a time, using the default Parser,
+// and calls fn with each task.
+func EachTask(r io.Reader, fn func(Task) error) error {
+	return Parser{}.EachTask(r, fn)
+}
+
+// EachTask parses r one line at a time and calls fn with each task,
+// without holding the whole list in memory. It stops at the first
+// parse error, or the first error returned by fn, and returns it.
+// Indented lines following a task are added to its Notes.
+func (p Parser) EachTask(r io.Reader, fn func(Task) error) error {
+	return p.eachTask(r, fn, nil)
+}
+
+// A ParseStats counts the kinds of lines read by FromReaderStats.
+type ParseStats struct {
+	Tasks    int
+	Blank    int
+	Comments int
+	Total    int // every line, including notes
+}
+
+// FromReaderStats is like FromReader, but also counts the lines read,
+// using the default Parser.
+func FromReaderStats(r io.Reader) (TaskList, ParseStats, error) {
+	return Parser{}.FromReaderStats(r)
+}
+
+// FromReaderStats is like FromReader, but also counts the lines read.
+func (p Parser) FromReaderStats(r io.Reader) (TaskList, ParseStats, error) {
+	var ret TaskList
+	var st ParseStats
+	err := p.eachTask(r, func(t Task) error {
+		ret = append(ret, t)
+		return nil
+	}, &st)
+	if err != nil {
+		return nil, st, err
+	}
+	return ret, st, nil
+}
+
+// eachTask is EachTask, counting lines in st if it is not nil.
+func (p Parser) eachTask(r io.Reader, fn func(Task) error, st *ParseStats) error {
+	if st == nil {
+		st = new(ParseStats)
+	}
+	// a task is held until we know no more notes follow it
+	var pending *Task
+	flush := func() error {
+		if pending == nil {
+			return nil
+		}
+		t := *pending
+		pending = nil
+		return fn(t)
+	}
+	err := eachLine(r, func(line string, lno int) error {
+		st.Total++
+		if !p.KeepComments && p.isComment(line) {
+			if len(strings.TrimSpace(line)) == 0 {
+				st.Blank++
+			} else {
+				st.Comments++
+			}
+			return nil
+		}
+		if pending != nil && isNote(line) {
+			pending.Notes = append(pending.Notes, strings.TrimSpace(line))
+			return nil
+		}
+		if err := flush(); err != nil {
+			return err
+		}
+		todo, err := p.Parse(line)
+		if err != nil {
+			return lineError(err, lno)
+		}
+		todo.index = lno
+		pending = &todo
+		st.Tasks++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return flush()
+}
+
+// eachLine calls fn with each line of r and its line number, stopping
+// if fn returns an error. A leading byte order mark and the carriage
+// returns of CRLF line endings are removed.
+func eachLine(r io.Reader, fn func(line string, lno int) error) error {
+	s := bufio.NewScanner(r)
+	lno := 1
+	for s.Scan() {
+		line := strings.TrimSuffix(s.Text(), "\r")
+		if lno == 1 {
+			line = strings.TrimPrefix(line, "\uFEFF")
+		}
+		if err := fn(line, lno); err != nil {
+			return err
+		}
+		lno++
+	}
+	return s.Err()
+}
+
+// isComment reports whether line is blank or a # comment. If # is
+// used as a sigil, only blank lines are comments.
+func (p Parser) isComment(line string) bool {
+	line = strings.TrimLeftFunc(line, unicode.IsSpace)
+	if len(line) == 0 {
+		return true
+	}
+	return line[0] == '#' && p.tagSigil() != '#' && p.contextSigil() != '#'
+}
+
+// isNote reports whether line is indented, continuing the task
+// before it.
+func isNote(line string) bool {
+	return len(strings.TrimSpace(line)) > 0 && (line[0] == ' ' || line[0] == '\t')
+}
+
+// lineError annotates a parse error with its line number.
+func lineError(err error, lno int) error {
+	if perr, ok := err.(*ParseError); ok {
+		perr.Line = lno
+		return perr
+	}
+	return fmt.Errorf("%v on line %v", err, lno)
+}
+
+// FromReaderAll is like FromReader, but keeps going after a bad line.
+// It returns every task that parsed, along with an error for each line
+// that did not. The errors are empty if the whole input was clean.
+func FromReaderAll(r io.Reader) (TaskList, []error) {
+	return Parser{}.FromReaderAll(r)
+}
+
+// FromReaderAll is like FromReader, but keeps going after a bad line.
+func (p Parser) FromReaderAll(r io.Reader) (TaskList, []error) {
+	var ret TaskList
+	var errs []error
+	// whether the last line read was a task, which notes may follow
+	afterTask := false
+	err := eachLine(r, func(line string, lno int) error {
+		if !p.KeepComments && p.isComment(line) {
+			return nil
+		}
+		if afterTask && isNote(line) {
+			last := &ret[len(ret)-1]
+			last.Notes = append(last.Notes, strings.TrimSpace(line))
+			return nil
+		}
+		todo, err := p.Parse(line)
+		if err != nil {
+			errs = append(errs, lineError(err, lno))
+		} else {
+			todo.index = lno
+			ret = append(ret, todo)
+		}
+		afterTask = err == nil
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return ret, errs
+}
+
+// ToWriter writes each task in the list to w as a parseable line,
+// in the order they appear in the list.
+func (l TaskList) ToWriter(w io.Writer) error {
+	for _, t := range l {
+		if _, err := fmt.Fprintln(w, t.UnParse()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Bytes returns the list as ToWriter would write it.
+func (l TaskList) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := l.ToWriter(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Archive writes the done tasks in the list to done, and the rest to
+// pending, as ToWriter does. Either writer may be nil to discard
+// those tasks.
+func (l TaskList) Archive(done, pending io.Writer) error {
+	d, p := l.Partition()
+	if done != nil {
+		if err := d.ToWriter(done); err != nil {
+			return err
+		}
+	}
+	if pending != nil {
+		if err := p.ToWriter(pending); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Filter returns a new tasklist containing all of the tasks that
+// match the query
+func (ts TaskList) Filter(query string) TaskList {
+	var ret TaskList
+	for _, t := range ts {
+		if t.Matches(query) {
+			ret = append(ret, t)
+		}
+	}
+	return ret
+}
+
+// FilterNot returns a new tasklist containing all of the tasks that
+// do not match the query
+func (ts TaskList) FilterNot(query string) TaskList {
+	var ret TaskList
+	for _, t := range ts {
+		if !t.Matches(query) {
+			ret = append(ret, t)
+		}
+	}
+	return ret
+}
+
+// FilterFunc returns a new tasklist containing all of the tasks
+// for which pred returns true.
+func (ts TaskList) FilterFunc(pred func(Task) bool) TaskList {
+	var ret TaskList
+	for _, t := range ts {
+		if pred(t) {
+			ret = append(ret, t)
+		}
+	}
+	return ret
+}
+
+// Partition splits the list into done and pending tasks. Both
+// lists keep the order of the original.
+func (ts TaskList) Partition() (done, pending TaskList) {
+	for _, t := range ts {
+		if t.Done {
+			done = append(done, t)
+		} else {
+			pending = append(pending, t)
+		}
+	}
+	return done, pending
+}
+
+// FilterUnblocked returns a new tasklist containing all of the tasks
+// that are not blocked.
+func (ts TaskList) FilterUnblocked() TaskList {
+	return ts.FilterFunc(func(t Task) bool { return !t.Blocked() })
+}
+
+// Inbox returns a new tasklist containing the not done tasks that
+// have not been processed: they have no tags, contexts, due date or
+// start date.
+func (ts TaskList) Inbox() TaskList {
+	return ts.FilterFunc(func(t Task) bool {
+		return !t.Done && len(t.Tags) == 0 && len(t.Contexts) == 0 &&
+			t.Due.IsZero() && t.Start.IsZero()
+	})
+}
+
+// FilterFold is like Filter, but matches titles case-insensitively.
+func (ts TaskList) FilterFold(query string) TaskList {
+	var ret TaskList
+	for _, t := range ts {
+		if t.MatchesFold(query) {
+			ret = append(ret, t)
+		}
+	}
+	return ret
+}
+
+// FilterFuzzy returns a new tasklist containing all of the tasks
+// whose titles match query within maxDist edits, as MatchesFuzzy.
+func (ts TaskList) FilterFuzzy(query string, maxDist int) TaskList {
+	return ts.FilterFunc(func(t Task) bool { return t.MatchesFuzzy(query, maxDist) })
+}
+
+// FilterAny returns a new tasklist containing all of the tasks that
+// match at least one of the queries. Each task appears at most once.
+func (ts TaskList) FilterAny(queries ...string) TaskList {
+	var ret TaskList
+	for _, t := range ts {
+		for _, q := range queries {
+			if t.Matches(q) {
+				ret = append(ret, t)
+				break
+			}
+		}
+	}
+	return ret
+}
+
+// WithDue returns a new tasklist containing all of the tasks that
+// have a due date.
+func (ts TaskList) WithDue() TaskList {
+	return ts.FilterFunc(func(t Task) bool { return !t.Due.IsZero() })
+}
+
+// WithoutDue returns a new tasklist containing all of the tasks that
+// have no due date.
+func (ts TaskList) WithoutDue() TaskList {
+	return ts.FilterFunc(func(t Task) bool { return t.Due.IsZero() })
+}
+
+// FilterDueRange returns a new tasklist containing all of the tasks
+// due within [from, to], inclusive. Tasks without a due date are
+// excluded. A zero from or to leaves that end of the range open.
+func (ts TaskList) FilterDueRange(from, to time.Time) TaskList {
+	var ret TaskList
+	for _, t := range ts {
+		if t.Due.IsZero() {
+			continue
+		}
+		if !from.IsZero() && t.Due.Before(from) {
+			continue
+		}
+		if !to.IsZero() && t.Due.After(to) {
+			continue
+		}
+		ret = append(ret, t)
+	}
+	return ret
+}
+
+// FilterStartRange is like FilterDueRange, but selects tasks by
+// their start dates. Tasks without a start date are excluded.
+func (ts TaskList) FilterStartRange(from, to time.Time) TaskList {
+	return ts.FilterFunc(func(t Task) bool {
+		return !t.Start.IsZero() &&
+			(from.IsZero() || !t.Start.Before(from)) &&
+			(to.IsZero() || !t.Start.After(to))
+	})
+}
+
+// CompletedBetween returns a new tasklist containing the done tasks
+// completed within [from, to], inclusive, ordered by completion date.
+// Tasks without a completion date are excluded. As with
+// FilterDueRange, a zero from or to leaves that end open.
+func (ts TaskList) CompletedBetween(from, to time.Time) TaskList {
+	ret := ts.FilterFunc(func(t Task) bool {
+		return t.Done && !t.Completed.IsZero() &&
+			(from.IsZero() || !t.Completed.Before(from)) &&
+			(to.IsZero() || !t.Completed.After(to))
+	})
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].Completed.Before(ret[j].Completed)
+	})
+	return ret
+}
+
+// GroupByTag returns the tasks in the list bucketed by tag. A task
+// with several tags appears in each of their buckets, and tasks with
+// no tags are grouped under the empty string. Each bucket keeps the
+// order of the list.
+func (ts TaskList) GroupByTag() map[string]TaskList {
+	ret := make(map[string]TaskList)
+	for _, t := range ts {
+		if len(t.Tags) == 0 {
+			ret[""] = append(ret[""], t)
+		}
+		for _, tag := range t.Tags {
+			ret[tag] = append(ret[tag], t)
+		}
+	}
+	return ret
+}
+
+// AllTags returns every tag used in the list, sorted, without repeats.
+func (ts TaskList) AllTags() []string {
+	var all []string
+	for _, t := range ts {
+		all = append(all, t.Tags...)
+	}
+	return sortedUnique(all)
+}
+
+// AllContexts returns every context used in the list, sorted, without
+// repeats.
+func (ts TaskList) AllContexts() []string {
+	var all []string
+	for _, t := range ts {
+		all = append(all, t.Contexts...)
+	}
+	return sortedUnique(all)
+}
+
+func sortedUnique(set []string) []string {
+	sort.Strings(set)
+	var ret []string
+	for i, s := range set {
+		if i == 0 || s != set[i-1] {
+			ret = append(ret, s)
+		}
+	}
+	return ret
+}
+
+// ProjectProgress returns how many of the tasks tagged with tag are
+// done, and how many there are in all.
+func (ts TaskList) ProjectProgress(tag string) (done, total int) {
+	for _, t := range ts {
+		if elementof(tag, t.Tags) {
+			total++
+			if t.Done {
+				done++
+			}
+		}
+	}
+	return done, total
+}
+
+// CompletionRate returns the fraction of tasks in the list that are
+// done, or 0 if the list is empty.
+func (ts TaskList) CompletionRate() float64 {
+	if len(ts) == 0 {
+		return 0
+	}
+	return float64(ts.Stats().Done) / float64(len(ts))
+}
+
+// Stats counts the tasks in a list.
+type Stats struct {
+	Total   int
+	Done    int
+	Pending int
+	Overdue int // pending tasks due before today
+}
+
+// Stats returns counts of the tasks in the list.
+func (ts TaskList) Stats() Stats {
+	s := Stats{Total: len(ts)}
+	for _, t := range ts {
+		if t.Done {
+			s.Done++
+			continue
+		}
+		s.Pending++
+		if t.Overdue() {
+			s.Overdue++
+		}
+	}
+	return s
+}
+
+// NextActions returns, for each tag, its not-done task with the
+// earliest due date, ties broken by title. Tasks without a due date
+// come after those with one. Tags whose tasks are all done are left
+// out.
+func (ts TaskList) NextActions() map[string]Task {
+	ret := make(map[string]Task)
+	for _, t := range ts {
+		if t.Done {
+			continue
+		}
+		for _, tag := range t.Tags {
+			cur, ok := ret[tag]
+			if !ok {
+				ret[tag] = t
+				continue
+			}
+			b, eq := before(t.Due, cur.Due)
+			if b || eq && t.Title < cur.Title {
+				ret[tag] = t
+			}
+		}
+	}
+	return ret
+}
+
+// CountByContext returns the number of tasks in each context. A task
+// with several contexts is counted once in each, and tasks with no
+// contexts are counted under the empty string.
+func (ts TaskList) CountByContext() map[string]int {
+	ret := make(map[string]int)
+	for _, t := range ts {
+		if len(t.Contexts) == 0 {
+			ret[""]++
+		}
+		for _, context := range t.Contexts {
+			ret[context]++
+		}
+	}
+	return ret
+}
+
+// A TagCount is a tag or context and the number of times it is used.
+type TagCount struct {
+	Tag   string
+	Count int
+}
+
+// TagFrequencies returns the number of times each tag is used in the
+// list, most used first, and alphabetically among equal counts.
+func (ts TaskList) TagFrequencies() []TagCount {
+	return frequencies(ts, func(t Task) []string { return t.Tags })
+}
+
+// ContextFrequencies is like TagFrequencies, but counts contexts.
+func (ts TaskList) ContextFrequencies() []TagCount {
+	return frequencies(ts, func(t Task) []string { return t.Contexts })
+}
+
+func frequencies(ts TaskList, names func(Task) []string) []TagCount {
+	counts := make(map[string]int)
+	for _, t := range ts {
+		for _, name := range names(t) {
+			counts[name]++
+		}
+	}
+	ret := make([]TagCount, 0, len(counts))
+	for name, n := range counts {
+		ret = append(ret, TagCount{name, n})
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Count != ret[j].Count {
+			return ret[i].Count > ret[j].Count
+		}
+		return ret[i].Tag < ret[j].Tag
+	})
+	return ret
+}
+
+// DuplicateTitles returns the tasks whose titles appear more than
+// once in the list, compared case-insensitively, keyed by the title in
+// lower case. Each list keeps the order of the original.
+func (ts TaskList) DuplicateTitles() map[string]TaskList {
+	byTitle := make(map[string]TaskList)
+	for _, t := range ts {
+		key := strings.ToLower(t.Title)
+		byTitle[key] = append(byTitle[key], t)
+	}
+	ret := make(map[string]TaskList)
+	for title, tasks := range byTitle {
+		if len(tasks) > 1 {
+			ret[title] = tasks
+		}
+	}
+	return ret
+}
+
+// Dedup returns a new tasklist without duplicate tasks, keeping the
+// first of each. Tasks are duplicates if they have the same title,
+// done status, due and start dates, and the same tags and contexts
+// in any order.
+func (ts TaskList) Dedup() TaskList {
+	var ret TaskList
+	seen := make(map[string]bool)
+	for _, t := range ts {
+		k := t.dedupKey()
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		ret = append(ret, t)
+	}
+	return ret
+}
+
+// Merge returns the union of two tasklists, sorted, with duplicates
+// removed as by Dedup. If a task is done in one list and not done
+// in the other, as judged by title alone, only the done task is kept,
+// so that completions are never lost. Done and pending tasks with the
+// same title in a single list, such as a recurring task and its next
+// occurrence, are all kept.
+func (ts TaskList) Merge(other TaskList) TaskList {
+	// pending drops the pending tasks of l completed in done
+	pending := func(l, done TaskList) TaskList {
+		titles := make(map[string]bool)
+		for _, t := range done {
+			if t.Done {
+				titles[t.Title] = true
+			}
+		}
+		return l.FilterFunc(func(t Task) bool { return t.Done || !titles[t.Title] })
+	}
+
+	ret := append(pending(ts, other), pending(other, ts)...).Dedup()
+	ret.SortStable()
+	return ret
+}
+
+// Diff compares the list to other, matching tasks by ID. Tasks only
+// in other are added, tasks only in the list are removed, and tasks in
+// both are unchanged. A task whose content changed has a new ID, so it
+// is both removed and added. Repeated tasks are matched one for one.
+func (ts TaskList) Diff(other TaskList) (added, removed, unchanged TaskList) {
+	counts := make(map[string]int)
+	for _, t := range other {
+		counts[t.ID()]++
+	}
+	for _, t := range ts {
+		id := t.ID()
+		if counts[id] > 0 {
+			counts[id]--
+			unchanged = append(unchanged, t)
+		} else {
+			removed = append(removed, t)
+		}
+	}
+
+	counts = make(map[string]int)
+	for _, t := range ts {
+		counts[t.ID()]++
+	}
+	for _, t := range other {
+		id := t.ID()
+		if counts[id] > 0 {
+			counts[id]--
+		} else {
+			added = append(added, t)
+		}
+	}
+	return added, removed, unchanged
+}
+
+// dedupKey returns a string that is the same for tasks Dedup
+// considers duplicates.
+func (t Task) dedupKey() string {
+	tags := append([]string(nil), t.Tags...)
+	contexts := append([]string(nil), t.Contexts...)
+	sort.Strings(tags)
+	sort.Strings(contexts)
+	return strings.Join([]string{
+		t.Title,
+		fmt.Sprint(t.Done),
+		formatTimed(t.Due, t.dueTime),
+		formatTimed(t.Start, t.startTime),
+		strings.Join(tags, " "),
+		strings.Join(contexts, " "),
+	}, "\x00")
+}
+
+// TotalTitleWords returns the number of words in all of the titles
+// in the list.
+func (ts TaskList) TotalTitleWords() int {
+	n := 0
+	for _, t := range ts {
+		n += t.TitleWordCount()
+	}
+	return n
+}
+
+// ReplaceInTitle replaces every occurrence of old with new in the
+// titles of the tasks in the list, in place. It returns the number
+// of tasks changed.
+func (ts TaskList) ReplaceInTitle(old, new string) int {
+	if len(old) == 0 {
+		return 0
+	}
+	n := 0
+	for i := range ts {
+		if strings.Contains(ts[i].Title, old) {
+			ts[i].Title = strings.Replace(ts[i].Title, old, new, -1)
+			ts[i].dirty = true
+			n++
+		}
+	}
+	return n
+}
+
+// RenameTag renames the tag old to new on every task in the list,
+// in place. A task that already has new keeps only one copy of it.
+// It returns the number of tasks changed.
+func (ts TaskList) RenameTag(old, new string) int {
+	n := 0
+	for i := range ts {
+		if tags, ok := rename(ts[i].Tags, old, new); ok {
+			ts[i].Tags = tags
+			ts[i].dirty = true
+			n++
+		}
+	}
+	return n
+}
+
+// RenameContext renames the context old to new on every task in the
+// list, as RenameTag does for tags.
+func (ts TaskList) RenameContext(old, new string) int {
+	n := 0
+	for i := range ts {
+		if contexts, ok := rename(ts[i].Contexts, old, new); ok {
+			ts[i].Contexts = contexts
+			ts[i].dirty = true
+			n++
+		}
+	}
+	return n
+}
+
+// AddTag adds tag to every task in the list that lacks it, in place,
+// and returns the number of tasks changed. An empty tag changes
+// nothing. The tasks in a list from Filter and the like are copies,
+// so adding tags to them leaves the original list unchanged.
+func (ts TaskList) AddTag(tag string) int {
+	if len(tag) == 0 {
+		return 0
+	}
+	n := 0
+	for i := range ts {
+		if !elementof(tag, ts[i].Tags) {
+			// copy, so as not to write into a slice shared with the original
+			ts[i].Tags = append(append([]string(nil), ts[i].Tags...), tag)
+			ts[i].dirty = true
+			n++
+		}
+	}
+	return n
+}
+
+// RemoveTag removes tag from every task in the list that has it, in
+// place, and returns the number of tasks changed. As with AddTag,
+// removing tags from a filtered list leaves the original unchanged.
+func (ts TaskList) RemoveTag(tag string) int {
+	n := 0
+	for i := range ts {
+		if !elementof(tag, ts[i].Tags) {
+			continue
+		}
+		var tags []string
+		for _, t := range ts[i].Tags {
+			if t != tag {
+				tags = append(tags, t)
+			}
+		}
+		ts[i].Tags = tags
+		ts[i].dirty = true
+		n++
+	}
+	return n
 }
 
-// returns before, equal
-func before(a, b time.Time) (bool, bool) {
-	if a.Equal(b) {
-		return false, true
+// rename returns a copy of set with old replaced by new, keeping only
+// the first occurrence of new, and whether old was present.
+func rename(set []string, old, new string) ([]string, bool) {
+	if old == new || !elementof(old, set) {
+		return set, false
 	}
-	if a.IsZero() && !b.IsZero() {
-		return false, false
+	var ret []string
+	for _, s := range set {
+		if s == old {
+			s = new
+		}
+		if s != new || !elementof(new, ret) {
+			ret = append(ret, s)
+		}
 	}
-	if !a.IsZero() && b.IsZero() {
-		return true, false
+	return ret, true
+}
+
+// Equal reports whether the lists are the same length and each task
+// is Equal to the task at the same position in other.
+func (ts TaskList) Equal(other TaskList) bool {
+	if len(ts) != len(other) {
+		return false
 	}
-	if a.Before(b) {
-		return true, false
-	} else {
-		return false, false
+	for i := range ts {
+		if !ts[i].Equal(other[i]) {
+			return false
+		}
 	}
+	return true
 }
 
-func FromReader(r io.Reader) (TaskList, error) {
-	s := bufio.NewScanner(r)
-	var ret TaskList
-	lno := 1
-	for s.Scan() {
-		line := s.Text()
-		todo, err := Parse(line)
-		if err != nil {
-			return nil, fmt.Errorf("%v on line %v", err, lno)
+// Line returns the task that was read from line n of its file, so that
+// it can be changed in place. It returns false if no task in the list
+// came from that line.
+func (ts TaskList) Line(n int) (*Task, bool) {
+	for i := range ts {
+		if ts[i].index == n && n > 0 {
+			return &ts[i], true
 		}
-		todo.index = lno
-		ret = append(ret, todo)
-		lno++
 	}
-	return ret, nil
+	return nil, false
 }
 
-// Filter returns a new tasklist containing all of the tasks that
-// match the query
-func (ts TaskList) Filter(query string) TaskList {
-	var ret TaskList
-	for _, t := range ts {
-		if t.Matches(query) {
-			ret = append(ret, t)
-		}
+// Page returns a copy of up to limit tasks of the list, starting at
+// offset. An offset outside the list gives an empty list, and a
+// negative limit gives every task from offset on.
+func (ts TaskList) Page(offset, limit int) TaskList {
+	if offset < 0 || offset >= len(ts) {
+		return nil
 	}
-	return ret
+	end := len(ts)
+	if limit >= 0 && offset+limit < end {
+		end = offset + limit
+	}
+	return append(TaskList(nil), ts[offset:end]...)
 }
 
-// FilterNot returns a new tasklist containing all of the tasks that
-// do not match the query
-func (ts TaskList) FilterNot(query string) TaskList {
-	var ret TaskList
-	for _, t := range ts {
-		if !t.Matches(query) {
-			ret = append(ret, t)
-		}
+// Reindex renumbers the tasks in place to their positions in the
+// list, counted from 1, so that String shows contiguous numbers.
+// Line then looks tasks up by their new numbers.
+func (ts TaskList) Reindex() {
+	for i := range ts {
+		ts[i].index = i + 1
 	}
+}
+
+// Reindexed is like Reindex, but returns a renumbered copy of the
+// list, leaving the receiver unchanged.
+func (ts TaskList) Reindexed() TaskList {
+	ret := append(TaskList(nil), ts...)
+	ret.Reindex()
 	return ret
 }
 
@@ -114,65 +1004,470 @@ type Task struct {
 	index    int // line in file
 	Raw      string
 	Done     bool
+	Priority byte // 'A' through 'Z', or 0 if unset
+	// Completed and Created are only parsed on done tasks,
+	// as the leading dates following the completion marker.
+	Completed  time.Time
+	Created    time.Time
+	Recurrence Recurrence
+	Meta       map[string]string // other key:value pairs, unparsed verbatim
+	Notes      []string          // indented lines following the task
+	Flagged    bool              // marked important with a leading *
+	Source     string            // name of the file read by FromDir
 
 	original string
+	order    []Token // kinds and texts of the parsed tokens, in order
+	dirty    bool    // modified by a method since parsing
+
+	// dueTime and startTime record that Due and Start were parsed
+	// with times of day, which UnParse keeps
+	dueTime, startTime bool
+}
+
+// Equal reports whether t and other have the same title, done status,
+// and due and start dates, and the same sets of tags and contexts,
+// regardless of order or repetition.
+func (t Task) Equal(other Task) bool {
+	return t.Title == other.Title &&
+		t.Done == other.Done &&
+		t.Due.Equal(other.Due) &&
+		t.Start.Equal(other.Start) &&
+		sameSet(t.Tags, other.Tags) &&
+		sameSet(t.Contexts, other.Contexts)
+}
+
+// Clone returns a copy of the task that shares no memory with it,
+// so either may be modified without affecting the other.
+func (t Task) Clone() Task {
+	c := t
+	if t.Tags != nil {
+		c.Tags = append([]string(nil), t.Tags...)
+	}
+	if t.Contexts != nil {
+		c.Contexts = append([]string(nil), t.Contexts...)
+	}
+	if t.Notes != nil {
+		c.Notes = append([]string(nil), t.Notes...)
+	}
+	if t.Meta != nil {
+		c.Meta = make(map[string]string, len(t.Meta))
+		for k, v := range t.Meta {
+			c.Meta[k] = v
+		}
+	}
+	return c
+}
+
+// NormalizeTagsContexts lowercases the task's tags and contexts,
+// removing any that become duplicates. The first of each is kept.
+func (t *Task) NormalizeTagsContexts() {
+	tags := lowerUnique(t.Tags)
+	contexts := lowerUnique(t.Contexts)
+	if !equalStrings(tags, t.Tags) || !equalStrings(contexts, t.Contexts) {
+		t.dirty = true
+	}
+	t.Tags = tags
+	t.Contexts = contexts
+}
+
+func lowerUnique(set []string) []string {
+	if set == nil {
+		return nil
+	}
+	ret := []string{}
+	for _, s := range set {
+		s = strings.ToLower(s)
+		if !elementof(s, ret) {
+			ret = append(ret, s)
+		}
+	}
+	return ret
+}
+
+// Complete marks the task done. If it has no completion date, the
+// completion date is set to today.
+func (t *Task) Complete() {
+	t.dirty = true
+	t.Done = true
+	if t.Completed.IsZero() {
+		t.Completed = today()
+	}
+}
+
+// Reopen marks the task not done and clears its completion date.
+func (t *Task) Reopen() {
+	t.dirty = true
+	t.Done = false
+	t.Completed = time.Time{}
+}
+
+// Validate reports whether the task is well-formed. It returns an
+// error if the title is empty, the task is due before it starts, or
+// a tag or context contains whitespace.
+func (t Task) Validate() error {
+	if !t.HasTitle() {
+		return errors.New("todo: empty title")
+	}
+	if !t.Due.IsZero() && !t.Start.IsZero() && t.Due.Before(t.Start) {
+		return fmt.Errorf("todo: due date %v is before start date %v",
+			t.Due.Format(DateFormat), t.Start.Format(DateFormat))
+	}
+	for _, tag := range t.Tags {
+		if strings.IndexFunc(tag, unicode.IsSpace) >= 0 {
+			return fmt.Errorf("todo: tag %q contains whitespace", tag)
+		}
+	}
+	for _, context := range t.Contexts {
+		if strings.IndexFunc(context, unicode.IsSpace) >= 0 {
+			return fmt.Errorf("todo: context %q contains whitespace", context)
+		}
+	}
+	return nil
 }
 
 // DateFormat is YY-MM-DD, with no times, time zone, etc.
 const DateFormat = "2006-1-2"
 
-// Parse takes a string and parses it as todo.txt formatted todo item
+// A Parser parses tasks. The zero value parses like Parse.
+type Parser struct {
+	// DateLayout is the layout, as in package time, of dates.
+	// If empty, DateFormat is used.
+	DateLayout string
+
+	// TagSigil and ContextSigil mark tags and contexts.
+	// If zero, + and @ are used.
+	TagSigil     byte
+	ContextSigil byte
+
+	// Strict causes malformed tokens to be errors, as in ParseStrict.
+	Strict bool
+
+	// KeepComments causes blank lines and lines starting with #
+	// to be parsed as tasks when reading, rather than skipped.
+	KeepComments bool
+
+	// LowercaseTagsContexts causes tags and contexts to be normalized
+	// as by Task.NormalizeTagsContexts.
+	LowercaseTagsContexts bool
+
+	// SplitTagCommas causes a tag or context token like +a,b,c to be
+	// split on commas into several tags or contexts.
+	SplitTagCommas bool
+
+	// SortTagsContexts causes UnParse to write tags and contexts in
+	// alphabetical order, rather than the task's order.
+	SortTagsContexts bool
+
+	// DoneMarkers are leading tokens, such as X or [x], that mark a
+	// task done in addition to x. Tasks are always unparsed with x.
+	DoneMarkers []string
+}
+
+func (p Parser) isDoneMarker(token string) bool {
+	return token == "x" || elementof(token, p.DoneMarkers)
+}
+
+// names returns the tags or contexts named by a token starting with
+// a sigil, or none if it names none.
+func (p Parser) names(token string) []string {
+	if !p.SplitTagCommas {
+		if len(token) > 1 {
+			return []string{token[1:]}
+		}
+		return nil
+	}
+	var ret []string
+	for _, name := range strings.Split(token[1:], ",") {
+		if len(name) > 0 {
+			ret = append(ret, name)
+		}
+	}
+	return ret
+}
+
+// Parse takes a string and parses it as todo.txt formatted todo item,
+// using the default Parser.
 func Parse(r string) (Task, error) {
+	return Parser{}.Parse(r)
+}
+
+// ParseStrict is like Parse, but returns an error instead of adding
+// malformed tokens to the title: an s: or rec: token with a bad value,
+// or a bare @ or + sigil.
+func ParseStrict(r string) (Task, error) {
+	return Parser{Strict: true}.Parse(r)
+}
+
+func (p Parser) layout() string {
+	if len(p.DateLayout) == 0 {
+		return DateFormat
+	}
+	return p.DateLayout
+}
+
+func (p Parser) tagSigil() byte {
+	if p.TagSigil == 0 {
+		return '+'
+	}
+	return p.TagSigil
+}
+
+func (p Parser) contextSigil() byte {
+	if p.ContextSigil == 0 {
+		return '@'
+	}
+	return p.ContextSigil
+}
+
+// date parses a date in the parser's layout.
+func (p Parser) date(s string) (time.Time, error) {
+	return time.ParseInLocation(p.layout(), s, time.Local)
+}
+
+// Parse takes a string and parses it as todo.txt formatted todo item
+func (p Parser) Parse(r string) (Task, error) {
 	if len(r) == 0 {
-		return Task{}, errors.New("todo: parse empty string")
+		return Task{}, &ParseError{Err: errors.New("todo: parse empty string")}
 	}
 
-	t := Task{Raw: r}
-	tokens := strings.Fields(r)
+	t := Task{Raw: r, original: r}
+	tokens := fields(r)
 	if len(tokens) == 0 {
-		return Task{}, errors.New("todo: parse only whitespace")
+		return Task{}, &ParseError{Err: errors.New("todo: parse only whitespace")}
+	}
+
+	fail := func(f field, err error) (Task, error) {
+		return Task{}, &ParseError{Col: f.off + 1, Token: f.s, Err: err}
 	}
+	last := tokens[len(tokens)-1]
 
-	if len(tokens) > 0 && tokens[0] == "x" {
+	if p.isDoneMarker(tokens[0].s) {
 		t.Done = true
 		tokens = tokens[1:]
 	}
 
 	if len(tokens) == 0 {
-		return Task{}, errors.New("todo: line contains only completion marker")
+		return fail(last, errors.New("todo: line contains only completion marker"))
+	}
+
+	if pri, ok := parsePriority(tokens[0].s); ok {
+		t.Priority = pri
+		tokens = tokens[1:]
+	}
+
+	if len(tokens) == 0 {
+		return fail(last, errors.New("todo: line contains only priority"))
+	}
+
+	if t.Done {
+		if date, err := p.date(tokens[0].s); err == nil {
+			t.Completed = date
+			tokens = tokens[1:]
+			if len(tokens) > 0 {
+				if date, err := p.date(tokens[0].s); err == nil {
+					t.Created = date
+					tokens = tokens[1:]
+				}
+			}
+		}
+		// UnParse writes the due date of an untitled task this way
+		// where a bare date would be read as one of the above
+		if len(tokens) > 0 && t.Created.IsZero() && strings.HasPrefix(tokens[0].s, "due:") {
+			if date, err := p.date(tokens[0].s[4:]); err == nil {
+				t.Due = date
+				t.order = append(t.order, Token{DueDateToken, tokens[0].s})
+				tokens = tokens[1:]
+			}
+		}
+		if len(tokens) == 0 && t.Due.IsZero() {
+			return fail(last, errors.New("todo: contains only done marker and completion time"))
+		}
+	}
+
+	if len(tokens) > 1 && tokens[0].s == "*" {
+		t.Flagged = true
+		tokens = tokens[1:]
 	}
 
-	for _, token := range tokens {
-		date, err := time.ParseInLocation(DateFormat, token, time.Local)
+	for _, f := range tokens {
+		token := f.s
+		date, err := p.date(token)
+		if d, ok := relativeDate(token); ok {
+			date, err = d, nil
+		} else if d, ok := weekDate(strings.TrimPrefix(token, "due:")); ok {
+			date, err = d, nil
+		}
+		timed := false
+		if strings.HasPrefix(token, "due:") {
+			if d, derr := dateTime(token[4:]); derr == nil {
+				date, err, timed = d, nil, true
+			}
+		}
+		kind, n := TitleToken, 1
 		switch {
+		case len(token) > 1 && token[0] == '\\':
+			t.Title = addToTitle(t.Title, token[1:])
+		case err == nil && !t.Due.IsZero():
+			return fail(f, errors.New("todo: multiple due dates"))
 		case err == nil:
 			t.Due = date
-		case strings.HasPrefix(token, "@"):
-			if len(token[1:]) > 0 {
-				t.Contexts = append(t.Contexts, token[1:])
+			t.dueTime = timed
+			kind = DueDateToken
+		case token[0] == p.contextSigil():
+			if names := p.names(token); len(names) > 0 {
+				t.Contexts = append(t.Contexts, names...)
+				kind, n = ContextToken, len(names)
+			} else if p.Strict {
+				return fail(f, errors.New("todo: empty context"))
 			} else {
 				t.Title = addToTitle(t.Title, token)
 			}
-		case strings.HasPrefix(token, "+"):
-			if len(token[1:]) > 0 {
-				t.Tags = append(t.Tags, token[1:])
+		case token[0] == p.tagSigil():
+			if names := p.names(token); len(names) > 0 {
+				t.Tags = append(t.Tags, names...)
+				kind, n = TagToken, len(names)
+			} else if p.Strict {
+				return fail(f, errors.New("todo: empty tag"))
 			} else {
 				t.Title = addToTitle(t.Title, token)
 			}
 		case strings.HasPrefix(token, "s:"):
-			start, err := time.ParseInLocation(DateFormat, token[2:], time.Local)
+			start, err := p.date(token[2:])
+			if err != nil {
+				start, err = dateTime(token[2:])
+				timed = err == nil
+			}
 			if err == nil {
 				t.Start = start
+				t.startTime = timed
+				kind = StartDateToken
+			} else if p.Strict {
+				return fail(f, fmt.Errorf("todo: bad start date %q", token[2:]))
+			} else {
+				t.Title = addToTitle(t.Title, token)
+			}
+		case strings.HasPrefix(token, "pri:") && isPriorityLetter(token[4:]):
+			// a (A) prefix or earlier pri: takes precedence
+			if t.Priority == 0 {
+				t.Priority = token[4]
+			}
+			continue
+		case strings.HasPrefix(token, "rec:"):
+			rec, err := parseRecurrence(token[4:])
+			if err == nil {
+				t.Recurrence = rec
+				kind = RecurrenceToken
+			} else if p.Strict {
+				return fail(f, err)
 			} else {
 				t.Title = addToTitle(t.Title, token)
 			}
+		case isMeta(token):
+			if t.Meta == nil {
+				t.Meta = make(map[string]string)
+			}
+			i := strings.Index(token, ":")
+			t.Meta[token[:i]] = token[i+1:]
+			kind = MetaToken
 		default:
 			t.Title = addToTitle(t.Title, token)
 		}
+		// one slot per tag or context, if a token names several
+		for i := 0; i < n; i++ {
+			t.order = append(t.order, Token{kind, token})
+		}
+	}
+	// only pri: tokens were left, which unparse as a leading priority
+	if len(t.order) == 0 {
+		return fail(last, errors.New("todo: line contains only priority"))
+	}
+	if p.LowercaseTagsContexts {
+		t.NormalizeTagsContexts()
 	}
 	return t, nil
 }
 
+// A ParseError describes a task that could not be parsed.
+type ParseError struct {
+	Line  int    // line in file, counted from 1, or 0 if unknown
+	Col   int    // byte offset of Token in the line, counted from 1
+	Token string // the offending token, if any
+	Err   error
+}
+
+func (e *ParseError) Error() string {
+	msg := e.Err.Error()
+	switch {
+	case e.Line > 0 && len(e.Token) > 0:
+		msg += fmt.Sprintf(" on line %v, column %v", e.Line, e.Col)
+	case e.Line > 0:
+		msg += fmt.Sprintf(" on line %v", e.Line)
+	}
+	return msg
+}
+
+func (e *ParseError) Unwrap() error { return e.Err }
+
+// A field is a whitespace separated token and its byte offset.
+type field struct {
+	s   string
+	off int
+}
+
+// fields splits s like strings.Fields, recording where each token starts.
+func fields(s string) []field {
+	var ret []field
+	start := -1
+	for i, r := range s {
+		if unicode.IsSpace(r) {
+			if start >= 0 {
+				ret = append(ret, field{s[start:i], start})
+				start = -1
+			}
+		} else if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		ret = append(ret, field{s[start:], start})
+	}
+	return ret
+}
+
+// parsePriority reports whether token is a priority marker like (A),
+// and if so, returns the priority letter.
+func parsePriority(token string) (byte, bool) {
+	if len(token) != 3 || token[0] != '(' || token[2] != ')' {
+		return 0, false
+	}
+	if !isPriorityLetter(token[1:2]) {
+		return 0, false
+	}
+	return token[1], true
+}
+
+// isPriorityLetter reports whether s is a single letter A through Z.
+func isPriorityLetter(s string) bool {
+	return len(s) == 1 && s[0] >= 'A' && s[0] <= 'Z'
+}
+
+// isMeta reports whether token is a key:value pair. The key must be
+// alphanumeric and the value may not contain another colon or start
+// with a slash, so that URLs stay in the title.
+func isMeta(token string) bool {
+	i := strings.Index(token, ":")
+	if i <= 0 || i == len(token)-1 {
+		return false
+	}
+	for _, r := range token[:i] {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	value := token[i+1:]
+	return !strings.Contains(value, ":") && value[0] != '/'
+}
+
 func addToTitle(title string, a string) string {
 	if len(title) > 0 {
 		title += " "
@@ -180,33 +1475,122 @@ func addToTitle(title string, a string) string {
 	return title + a
 }
 
+// ID returns a short identifier derived from the task's content:
+// the first 8 hex digits of the SHA-256 of UnParse. Unlike the line
+// number, it does not change when the file is reordered.
+func (t Task) ID() string {
+	sum := sha256.Sum256([]byte(t.UnParse()))
+	return hex.EncodeToString(sum[:4])
+}
+
+// BlockedTag is the tag that marks a task as blocked, waiting on
+// something else.
+var BlockedTag = "waiting"
+
+// Blocked reports whether the task is tagged with BlockedTag.
+func (t Task) Blocked() bool {
+	return elementof(BlockedTag, t.Tags)
+}
+
+// HasTitle reports whether the task has a title other than whitespace.
+func (t Task) HasTitle() bool {
+	return len(strings.TrimSpace(t.Title)) > 0
+}
+
+// PriorityWeight returns the task's priority as a number, from 26
+// for (A) down to 1 for (Z), or 0 if it has none.
+func (t Task) PriorityWeight() int {
+	if t.Priority < 'A' || t.Priority > 'Z' {
+		return 0
+	}
+	return int('Z'-t.Priority) + 1
+}
+
+// HasTag reports whether the task is tagged with tag.
+func (t Task) HasTag(tag string) bool {
+	return len(tag) > 0 && elementof(tag, t.Tags)
+}
+
+// HasContext reports whether the task is in context ctx.
+func (t Task) HasContext(ctx string) bool {
+	return len(ctx) > 0 && elementof(ctx, t.Contexts)
+}
+
+// HasDue reports whether the task has a due date.
+func (t Task) HasDue() bool {
+	return !t.Due.IsZero()
+}
+
+// HasStart reports whether the task has a start date.
+func (t Task) HasStart() bool {
+	return !t.Start.IsZero()
+}
+
+// TitleWordCount returns the number of whitespace separated words
+// in the title.
+func (t Task) TitleWordCount() int {
+	return len(strings.Fields(t.Title))
+}
+
+// TitleOnly returns the words of the title, separated by single
+// spaces, leaving out any that look like tags, contexts, dates, or
+// priorities, such as escaped words or bare sigils.
+func (t Task) TitleOnly() string {
+	var words []string
+	for _, w := range strings.Fields(t.Title) {
+		if _, pri := parsePriority(w); pri || w[0] == '@' || w[0] == '+' {
+			continue
+		}
+		if _, err := (Parser{}).date(w); err == nil {
+			continue
+		}
+		if _, ok := relativeDate(w); ok {
+			continue
+		}
+		words = append(words, w)
+	}
+	return strings.Join(words, " ")
+}
+
 // UnParse converts a task into a parseable string
 // This may not be the same string as the original,
 // but they will parse to the same task.
+// Notes follow on indented lines, as FromReader reads them.
 func (t Task) UnParse() string {
-	var line string
-	if t.Done {
-		line += "x "
-	}
-	line += t.Title
-	if !t.Due.IsZero() {
-		line += " " + t.Due.Format(DateFormat)
-	}
-	if !t.Start.IsZero() {
-		line += " s:" + t.Start.Format(DateFormat)
-	}
+	return Parser{}.UnParse(t)
+}
 
-	for _, context := range t.Contexts {
-		line += " @" + context
+// UnParse is like Task.UnParse, but uses the parser's date layout
+// and sigils, so that p.Parse can read the result.
+func (p Parser) UnParse(t Task) string {
+	tokens := p.Tokens(t)
+	texts := make([]string, len(tokens))
+	for i, tok := range tokens {
+		texts[i] = tok.Text
 	}
+	return t.withNotes(strings.Join(texts, " "))
+}
 
-	for _, tag := range t.Tags {
-		line += " +" + tag
+// withNotes appends the task's notes to line, each on its own
+// indented line.
+func (t Task) withNotes(line string) string {
+	for _, note := range t.Notes {
+		line += "\n  " + note
 	}
-
 	return line
 }
 
+// UnParseOriginal returns the line the task was parsed from, exactly,
+// unless the task has since been changed by one of its methods or was
+// not parsed at all, in which case it returns UnParse.
+// Changes made by assigning to fields directly are not tracked.
+func (t Task) UnParseOriginal() string {
+	if t.dirty || len(t.original) == 0 {
+		return t.UnParse()
+	}
+	return t.withNotes(t.original)
+}
+
 func (t Task) String() string {
 	done := ""
 	if t.Done {
@@ -236,6 +1620,18 @@ func (t Task) String() string {
 	return out
 }
 
+// String returns each task's String, one per line.
+func (ts TaskList) String() string {
+	lines := make([]string, len(ts))
+	for i, t := range ts {
+		lines[i] = t.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Matches reports whether the task matches a single query term.
+// A term starting with @ matches a context, one starting with +
+// matches a tag, and anything else matches a substring of the title.
 func (t Task) Matches(query string) bool {
 	if len(query) == 0 {
 		return true
@@ -250,6 +1646,100 @@ func (t Task) Matches(query string) bool {
 	}
 }
 
+// MatchesFold is like Matches, but title queries are compared
+// case-insensitively. Contexts and tags must still match exactly.
+func (t Task) MatchesFold(query string) bool {
+	if len(query) > 0 && (query[0] == '@' || query[0] == '+') {
+		return t.Matches(query)
+	}
+	return strings.Contains(strings.ToLower(t.Title), strings.ToLower(query))
+}
+
+// MatchesAll splits query on whitespace and reports whether the
+// task matches every term, as Matches. A term starting with - is
+// negated: the task must not match the rest of it.
+func (t Task) MatchesAll(query string) bool {
+	for _, term := range strings.Fields(query) {
+		if len(term) > 1 && term[0] == '-' {
+			if t.Matches(term[1:]) {
+				return false
+			}
+		} else if !t.Matches(term) {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchesFuzzy reports whether query is within maxDist edits
+// (insertions, deletions or substitutions of a rune) of some
+// contiguous part of the title.
+func (t Task) MatchesFuzzy(query string, maxDist int) bool {
+	return substringDistance([]rune(query), []rune(t.Title)) <= maxDist
+}
+
+// substringDistance returns the smallest Levenshtein distance between
+// pattern and any substring of text.
+func substringDistance(pattern, text []rune) int {
+	// prev[j] is the distance of the pattern so far from the best
+	// substring of text ending at j; a match may start anywhere, so row
+	// 0 is all zeros.
+	prev := make([]int, len(text)+1)
+	cur := make([]int, len(text)+1)
+	for i := 1; i <= len(pattern); i++ {
+		cur[0] = i
+		for j := 1; j <= len(text); j++ {
+			cost := 1
+			if pattern[i-1] == text[j-1] {
+				cost = 0
+			}
+			cur[j] = prev[j-1] + cost
+			if d := prev[j] + 1; d < cur[j] {
+				cur[j] = d
+			}
+			if d := cur[j-1] + 1; d < cur[j] {
+				cur[j] = d
+			}
+		}
+		prev, cur = cur, prev
+	}
+	best := prev[0]
+	for _, d := range prev {
+		if d < best {
+			best = d
+		}
+	}
+	return best
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// sameSet reports whether a and b contain the same strings,
+// ignoring order and repetition.
+func sameSet(a, b []string) bool {
+	for _, s := range a {
+		if !elementof(s, b) {
+			return false
+		}
+	}
+	for _, s := range b {
+		if !elementof(s, a) {
+			return false
+		}
+	}
+	return true
+}
+
 func elementof(item string, set []string) bool {
 	for _, i := range set {
 		if i == item {
@@ -4,7 +4,15 @@
 
 package todo
 
-import "testing"
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
 
 func TestString(t *testing.T) {
 	todos := []struct {
@@ -23,6 +31,21 @@ func TestString(t *testing.T) {
 	}
 }
 
+func TestListString(t *testing.T) {
+	l, err := FromReader(strings.NewReader("foo 2014-3-5 @home +a +b\nx bar s:2014-3-1\n"))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+	expect := "1\t\tfoo\t2014-3-5\t\thome\ta, b\t\n" +
+		"2\tx\tbar\t\t2014-3-1\t\t\t"
+	if got := l.String(); got != expect {
+		t.Errorf("Got %q, expected %q", got, expect)
+	}
+	if got := TaskList(nil).String(); got != "" {
+		t.Errorf("Got %q for an empty list", got)
+	}
+}
+
 func TestParse(t *testing.T) {
 	errors := []struct {
 		in     string
@@ -59,3 +82,2091 @@ func TestParse(t *testing.T) {
 		}
 	}
 }
+
+func TestPriority(t *testing.T) {
+	cases := []struct {
+		in       string
+		priority byte
+		title    string
+	}{
+		{"(A) foo", 'A', "foo"},
+		{"x (B) foo", 'B', "foo"},
+		{"(Z) foo bar", 'Z', "foo bar"},
+		{"(a) foo", 0, "(a) foo"},
+		{"foo (A)", 0, "foo (A)"},
+		{"(AB) foo", 0, "(AB) foo"},
+	}
+
+	for _, cas := range cases {
+		todo, err := Parse(cas.in)
+		if err != nil {
+			t.Errorf("On case %v, unexpected parse error %v", cas.in, err)
+			continue
+		}
+		if todo.Priority != cas.priority {
+			t.Errorf("On case %v, got priority %q (expected %q)", cas.in, todo.Priority, cas.priority)
+		}
+		if todo.Title != cas.title {
+			t.Errorf("On case %v, got title %v (expected %v)", cas.in, todo.Title, cas.title)
+		}
+		if out := todo.UnParse(); out != cas.in {
+			t.Errorf("On case %v, unparsed to %v", cas.in, out)
+		}
+	}
+
+	if _, err := Parse("(A)"); err == nil {
+		t.Errorf("On case (A), got no error")
+	}
+}
+
+func TestLessPriority(t *testing.T) {
+	cases := []struct {
+		in     []string
+		expect []string
+	}{
+		{
+			[]string{"c", "(B) b", "(A) a"},
+			[]string{"(A) a", "(B) b", "c"},
+		},
+		{
+			[]string{"b 2014-1-1", "(C) c 2015-1-1", "a"},
+			[]string{"(C) c 2015-1-1", "b 2014-1-1", "a"},
+		},
+		{
+			[]string{"x (A) done", "(B) b", "todo"},
+			[]string{"(B) b", "todo", "x (A) done"},
+		},
+		{
+			[]string{"(A) b", "(A) a"},
+			[]string{"(A) a", "(A) b"},
+		},
+		{
+			[]string{"(A) a 2014-1-1", "(A) * b 2014-1-1"},
+			[]string{"(A) * b 2014-1-1", "(A) a 2014-1-1"},
+		},
+		{
+			[]string{"a", "* b", "* a 2014-1-1", "(A) c"},
+			[]string{"(A) c", "* a 2014-1-1", "* b", "a"},
+		},
+		{
+			[]string{"* b", "* a"},
+			[]string{"* a", "* b"},
+		},
+	}
+
+	for _, cas := range cases {
+		var l TaskList
+		for _, in := range cas.in {
+			task, err := Parse(in)
+			if err != nil {
+				t.Fatalf("On case %v, unexpected parse error %v", in, err)
+			}
+			l = append(l, task)
+		}
+		sort.Sort(l)
+		for i := range l {
+			if got := l[i].UnParse(); got != cas.expect[i] {
+				t.Errorf("On case %v, position %v got %v (expected %v)", cas.in, i, got, cas.expect[i])
+			}
+		}
+	}
+}
+
+func TestCompletionDates(t *testing.T) {
+	cases := []struct {
+		in        string
+		completed string
+		created   string
+		due       string
+	}{
+		{"x buy milk", "", "", ""},
+		{"x 2014-1-2 buy milk", "2014-1-2", "", ""},
+		{"x 2014-1-2 2013-12-30 buy milk", "2014-1-2", "2013-12-30", ""},
+		{"x (A) 2014-1-2 2013-12-30 buy milk 2014-1-1", "2014-1-2", "2013-12-30", "2014-1-1"},
+		{"2014-1-2 buy milk", "", "", "2014-1-2"},
+	}
+
+	format := func(d time.Time) string {
+		if d.IsZero() {
+			return ""
+		}
+		return d.Format(DateFormat)
+	}
+
+	for _, cas := range cases {
+		todo, err := Parse(cas.in)
+		if err != nil {
+			t.Errorf("On case %v, unexpected parse error %v", cas.in, err)
+			continue
+		}
+		if got := format(todo.Completed); got != cas.completed {
+			t.Errorf("On case %v, got completed %v (expected %v)", cas.in, got, cas.completed)
+		}
+		if got := format(todo.Created); got != cas.created {
+			t.Errorf("On case %v, got created %v (expected %v)", cas.in, got, cas.created)
+		}
+		if got := format(todo.Due); got != cas.due {
+			t.Errorf("On case %v, got due %v (expected %v)", cas.in, got, cas.due)
+		}
+		again, err := Parse(todo.UnParse())
+		if err != nil {
+			t.Errorf("On case %v, unexpected error reparsing %v", cas.in, err)
+			continue
+		}
+		if !again.Completed.Equal(todo.Completed) || !again.Created.Equal(todo.Created) {
+			t.Errorf("On case %v, dates did not round trip through %v", cas.in, todo.UnParse())
+		}
+	}
+}
+
+func TestToWriter(t *testing.T) {
+	in := "(A) call mom @phone\nx 2014-1-2 buy milk\nwrite novel 2015-12-31 s:2015-12-30 +art\n"
+	l, err := FromReader(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := l.ToWriter(&buf); err != nil {
+		t.Fatalf("unexpected write error %v", err)
+	}
+	if buf.String() != in {
+		t.Errorf("Got %q, expected %q", buf.String(), in)
+	}
+
+	again, err := FromReader(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error rereading %v", err)
+	}
+	if len(again) != len(l) {
+		t.Fatalf("Got %v tasks, expected %v", len(again), len(l))
+	}
+	for i := range l {
+		if again[i].UnParse() != l[i].UnParse() {
+			t.Errorf("Got %v, expected %v", again[i].UnParse(), l[i].UnParse())
+		}
+	}
+
+	buf.Reset()
+	if err := (TaskList{}).ToWriter(&buf); err != nil || buf.Len() != 0 {
+		t.Errorf("Empty list wrote %q, %v", buf.String(), err)
+	}
+}
+
+func TestSortStable(t *testing.T) {
+	in := "milk 2014-1-1\nc later\nx done\nmilk 2014-1-1\neggs 2014-1-1\nmilk 2014-1-1\n"
+	l, err := FromReader(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+	l.SortStable()
+
+	expect := []int{5, 1, 4, 6, 2, 3}
+	for i := range l {
+		if l[i].index != expect[i] {
+			t.Errorf("Position %v got line %v (expected %v)", i, l[i].index, expect[i])
+		}
+	}
+}
+
+func TestMeta(t *testing.T) {
+	cases := []struct {
+		in      string
+		meta    map[string]string
+		title   string
+		unparse string
+	}{
+		{"foo id:2 area:home", map[string]string{"id": "2", "area": "home"}, "foo", "foo area:home id:2"},
+		{"area:home foo id:2", map[string]string{"id": "2", "area": "home"}, "foo", "foo area:home id:2"},
+		{"see http://x", nil, "see http://x", "see http://x"},
+		{"at 10:30:00", nil, "at 10:30:00", "at 10:30:00"},
+		{"a: :b foo", nil, "a: :b foo", "a: :b foo"},
+		{"foo s:2014-1-2", nil, "foo", "foo s:2014-1-2"},
+	}
+
+	for _, cas := range cases {
+		todo, err := Parse(cas.in)
+		if err != nil {
+			t.Errorf("On case %v, unexpected parse error %v", cas.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(todo.Meta, cas.meta) {
+			t.Errorf("On case %v, got meta %v (expected %v)", cas.in, todo.Meta, cas.meta)
+		}
+		if todo.Title != cas.title {
+			t.Errorf("On case %v, got title %v (expected %v)", cas.in, todo.Title, cas.title)
+		}
+		if got := todo.UnParse(); got != cas.unparse {
+			t.Errorf("On case %v, unparsed to %v (expected %v)", cas.in, got, cas.unparse)
+		}
+	}
+}
+
+func TestMetaRoundTrip(t *testing.T) {
+	cases := []struct {
+		in      string
+		meta    map[string]string
+		unparse string
+	}{
+		{
+			"foo due:2014-03-05 s:2014-3-1 t:2014-02-28 rec:1w pri:B uuid:AbC-123",
+			map[string]string{"due": "2014-03-05", "t": "2014-02-28", "uuid": "AbC-123"},
+			"(B) foo s:2014-3-1 rec:1w due:2014-03-05 t:2014-02-28 uuid:AbC-123",
+		},
+		{
+			"foo pri:b rec:often h:1",
+			map[string]string{"pri": "b", "h": "1"},
+			"foo rec:often h:1 pri:b",
+		},
+		{
+			"x 2014-3-6 foo Key:Value 2:3",
+			map[string]string{"Key": "Value", "2": "3"},
+			"x 2014-3-6 foo 2:3 Key:Value",
+		},
+	}
+
+	for _, cas := range cases {
+		todo, err := Parse(cas.in)
+		if err != nil {
+			t.Errorf("On case %v, unexpected parse error %v", cas.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(todo.Meta, cas.meta) {
+			t.Errorf("On case %v, got meta %v (expected %v)", cas.in, todo.Meta, cas.meta)
+		}
+		got := todo.UnParse()
+		if got != cas.unparse {
+			t.Errorf("On case %v, unparsed to %v (expected %v)", cas.in, got, cas.unparse)
+		}
+		again, err := Parse(got)
+		if err != nil || !reflect.DeepEqual(again.Meta, todo.Meta) || !again.Start.Equal(todo.Start) {
+			t.Errorf("On case %v, reparsed to %v %v, %v", cas.in, again.Meta, again.Start, err)
+		}
+	}
+}
+
+func TestFilterDueRange(t *testing.T) {
+	l, err := FromReader(strings.NewReader("a 2014-1-1\nb 2014-1-5\nc 2014-1-10\nd\n"))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+	date := func(s string) time.Time {
+		d, _ := time.ParseInLocation(DateFormat, s, time.Local)
+		return d
+	}
+
+	cases := []struct {
+		from, to time.Time
+		expect   string
+	}{
+		{date("2014-1-1"), date("2014-1-5"), "ab"},
+		{date("2014-1-2"), date("2014-1-9"), "b"},
+		{date("2014-1-5"), time.Time{}, "bc"},
+		{time.Time{}, date("2014-1-5"), "ab"},
+		{time.Time{}, time.Time{}, "abc"},
+		{date("2014-2-1"), time.Time{}, ""},
+	}
+
+	for _, cas := range cases {
+		var got string
+		for _, task := range l.FilterDueRange(cas.from, cas.to) {
+			got += task.Title
+		}
+		if got != cas.expect {
+			t.Errorf("On range %v-%v, got %v (expected %v)", cas.from, cas.to, got, cas.expect)
+		}
+	}
+}
+
+func TestFilterStartRange(t *testing.T) {
+	l, err := FromReader(strings.NewReader("a s:2014-1-1\nb s:2014-1-5 2014-2-1\nc s:2014-1-10\nd 2014-1-5\n"))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+	date := func(s string) time.Time {
+		d, _ := time.ParseInLocation(DateFormat, s, time.Local)
+		return d
+	}
+
+	cases := []struct {
+		from, to time.Time
+		expect   string
+	}{
+		{date("2014-1-1"), date("2014-1-5"), "ab"},
+		{date("2014-1-2"), date("2014-1-9"), "b"},
+		{date("2014-1-5"), time.Time{}, "bc"},
+		{time.Time{}, date("2014-1-5"), "ab"},
+		{time.Time{}, date("2013-12-31"), ""},
+		{time.Time{}, time.Time{}, "abc"},
+	}
+
+	for _, cas := range cases {
+		var got string
+		for _, task := range l.FilterStartRange(cas.from, cas.to) {
+			got += task.Title
+		}
+		if got != cas.expect {
+			t.Errorf("On range %v-%v, got %v (expected %v)", cas.from, cas.to, got, cas.expect)
+		}
+	}
+}
+
+func TestMatchesAll(t *testing.T) {
+	task, err := Parse("buy milk @home @store +urgent")
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+
+	cases := []struct {
+		query  string
+		expect bool
+	}{
+		{"", true},
+		{"milk", true},
+		{"@home +urgent milk", true},
+		{"  @store   buy ", true},
+		{"@home +urgent eggs", false},
+		{"@work +urgent milk", false},
+		{"@home +later milk", false},
+		{"buy milk", true},
+		{"milk buy", true},
+		{"+urgent -@work", true},
+		{"+urgent -@home", false},
+		{"@home -+later", true},
+		{"@home -+urgent", false},
+		{"milk -eggs", true},
+		{"milk -buy", false},
+		{"- milk", false},
+	}
+
+	for _, cas := range cases {
+		if got := task.MatchesAll(cas.query); got != cas.expect {
+			t.Errorf("On query %q, got %v (expected %v)", cas.query, got, cas.expect)
+		}
+	}
+
+	if task.Matches("milk buy") {
+		t.Errorf("Matches split a single query")
+	}
+}
+
+func TestFilterAny(t *testing.T) {
+	l, err := FromReader(strings.NewReader("a +work\nb +school\nc +work +school\nd +home\n"))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+
+	cases := []struct {
+		queries []string
+		expect  string
+	}{
+		{nil, ""},
+		{[]string{"+work"}, "ac"},
+		{[]string{"+work", "+school"}, "abc"},
+		{[]string{"+work", "+work", "c"}, "ac"},
+		{[]string{"+none"}, ""},
+	}
+
+	for _, cas := range cases {
+		var got string
+		for _, task := range l.FilterAny(cas.queries...) {
+			got += task.Title
+		}
+		if got != cas.expect {
+			t.Errorf("On queries %v, got %v (expected %v)", cas.queries, got, cas.expect)
+		}
+	}
+}
+
+func TestMatchesFold(t *testing.T) {
+	task, err := Parse("Buy Milk @home +Urgent")
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+
+	cases := []struct {
+		query  string
+		expect bool
+	}{
+		{"", true},
+		{"Milk", true},
+		{"milk", true},
+		{"MILK", true},
+		{"eggs", false},
+		{"@home", true},
+		{"@Home", false},
+		{"+Urgent", true},
+		{"+urgent", false},
+	}
+
+	for _, cas := range cases {
+		if got := task.MatchesFold(cas.query); got != cas.expect {
+			t.Errorf("On query %q, got %v (expected %v)", cas.query, got, cas.expect)
+		}
+	}
+
+	if n := len(TaskList{task}.FilterFold("milk")); n != 1 {
+		t.Errorf("FilterFold got %v tasks (expected 1)", n)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	jan1 := time.Date(2014, 1, 1, 0, 0, 0, 0, time.Local)
+	jan2 := time.Date(2014, 1, 2, 0, 0, 0, 0, time.Local)
+
+	cases := []struct {
+		todo   Task
+		expect string
+	}{
+		{Task{Title: "foo", Start: jan1, Due: jan2, Tags: []string{"a"}, Contexts: []string{"b"}}, ""},
+		{Task{Title: "foo", Start: jan1, Due: jan1}, ""},
+		{Task{Title: "foo", Due: jan1}, ""},
+		{Task{Title: " \t"}, "todo: empty title"},
+		{Task{Title: "foo", Start: jan2, Due: jan1}, "todo: due date 2014-1-1 is before start date 2014-1-2"},
+		{Task{Title: "foo", Tags: []string{"a b"}}, `todo: tag "a b" contains whitespace`},
+		{Task{Title: "foo", Contexts: []string{"a\tb"}}, `todo: context "a\tb" contains whitespace`},
+	}
+
+	for _, cas := range cases {
+		err := cas.todo.Validate()
+		switch {
+		case err == nil && cas.expect != "":
+			t.Errorf("On case %v, got no error (expected %v)", cas.todo.UnParse(), cas.expect)
+		case err != nil && err.Error() != cas.expect:
+			t.Errorf("On case %v, got %v (expected %q)", cas.todo.UnParse(), err, cas.expect)
+		}
+	}
+}
+
+func TestGroupByTag(t *testing.T) {
+	l, err := FromReader(strings.NewReader("a +work\nb\nc +work +home\nd +home\n"))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+
+	expect := map[string]string{
+		"work": "ac",
+		"home": "cd",
+		"":     "b",
+	}
+
+	groups := l.GroupByTag()
+	if len(groups) != len(expect) {
+		t.Errorf("Got %v groups, expected %v", len(groups), len(expect))
+	}
+	for tag, titles := range expect {
+		var got string
+		for _, task := range groups[tag] {
+			got += task.Title
+		}
+		if got != titles {
+			t.Errorf("On tag %q, got %v (expected %v)", tag, got, titles)
+		}
+	}
+}
+
+func TestCountByContext(t *testing.T) {
+	l, err := FromReader(strings.NewReader("a @home\nb\nc @home @phone\nx d @phone\ne\n"))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+
+	expect := map[string]int{"home": 2, "phone": 2, "": 2}
+	if got := l.CountByContext(); !reflect.DeepEqual(got, expect) {
+		t.Errorf("Got %v, expected %v", got, expect)
+	}
+}
+
+func TestParseStrict(t *testing.T) {
+	cases := []struct {
+		in      string
+		expect  string
+		lenient string
+	}{
+		{"foo s:notadate", `todo: bad start date "notadate"`, "foo s:notadate"},
+		{"foo @ bar", "todo: empty context", "foo @ bar"},
+		{"foo + bar", "todo: empty tag", "foo + bar"},
+		{"foo rec:often", `todo: bad recurrence unit in "often"`, "foo rec:often"},
+		{"foo s:2014-1-2 @home +work rec:1w", "", "foo"},
+	}
+
+	for _, cas := range cases {
+		_, err := ParseStrict(cas.in)
+		switch {
+		case err == nil && cas.expect != "":
+			t.Errorf("On case %v, got no error (expected %v)", cas.in, cas.expect)
+		case err != nil && err.Error() != cas.expect:
+			t.Errorf("On case %v, got %v (expected %v)", cas.in, err, cas.expect)
+		}
+
+		todo, err := Parse(cas.in)
+		if err != nil {
+			t.Errorf("On case %v, unexpected lenient parse error %v", cas.in, err)
+		} else if todo.Title != cas.lenient {
+			t.Errorf("On case %v, got lenient title %v (expected %v)", cas.in, todo.Title, cas.lenient)
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	_, err := ParseStrict("foo bar s:notadate +work")
+	perr, ok := err.(*ParseError)
+	if !ok {
+		t.Fatalf("Got %v, expected a *ParseError", err)
+	}
+	if perr.Col != 9 || perr.Token != "s:notadate" {
+		t.Errorf("Got column %v token %q, expected column 9 token %q", perr.Col, perr.Token, "s:notadate")
+	}
+
+	in := "  x\nfine\nok\n\tfoo\t@ bar\n"
+	_, err = FromReader(strings.NewReader(in))
+	perr, ok = err.(*ParseError)
+	if !ok {
+		t.Fatalf("Got %v, expected a *ParseError", err)
+	}
+	expect := "todo: line contains only completion marker on line 1, column 3"
+	if perr.Line != 1 || perr.Col != 3 || perr.Error() != expect {
+		t.Errorf("Got %v, expected %v", perr, expect)
+	}
+
+	_, err = Parser{KeepComments: true}.FromReader(strings.NewReader("fine\n\n"))
+	expect = "todo: parse empty string on line 2"
+	if err == nil || err.Error() != expect {
+		t.Errorf("Got %v, expected %v", err, expect)
+	}
+}
+
+func TestFromReaderAll(t *testing.T) {
+	in := "a\nx\nb\n\n(A)\nc\n"
+	l, errs := FromReaderAll(strings.NewReader(in))
+
+	var titles string
+	for _, task := range l {
+		titles += task.Title
+	}
+	if titles != "abc" {
+		t.Errorf("Got tasks %v, expected abc", titles)
+	}
+	if l[2].index != 6 {
+		t.Errorf("Got index %v for c, expected 6", l[2].index)
+	}
+
+	lines := []int{2, 5}
+	if len(errs) != len(lines) {
+		t.Fatalf("Got errors %v, expected errors on lines %v", errs, lines)
+	}
+	for i, err := range errs {
+		perr, ok := err.(*ParseError)
+		if !ok || perr.Line != lines[i] {
+			t.Errorf("Got %v, expected an error on line %v", err, lines[i])
+		}
+	}
+
+	if _, errs := FromReaderAll(strings.NewReader("a\nb\n")); len(errs) != 0 {
+		t.Errorf("Got errors %v on clean input", errs)
+	}
+}
+
+func TestDedup(t *testing.T) {
+	in := "a +x +y @home\nb\na @home +y +x\nx a +x +y @home\nb 2014-1-1\nb\nA +x +y @home\nc due:2024-01-02T15:00Z\nc due:2024-01-02T16:00Z\nc due:2024-01-02T15:00Z\n"
+	l, err := FromReader(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+
+	expect := []int{1, 2, 4, 5, 7, 8, 9}
+	got := l.Dedup()
+	if len(got) != len(expect) {
+		t.Fatalf("Got %v tasks, expected %v", len(got), len(expect))
+	}
+	for i := range got {
+		if got[i].index != expect[i] {
+			t.Errorf("Position %v got line %v (expected %v)", i, got[i].index, expect[i])
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	cases := []struct {
+		a, b   string
+		expect []string
+	}{
+		{
+			"b\na 2014-1-1\n",
+			"c\na 2014-1-1\n",
+			[]string{"a 2014-1-1", "b", "c"},
+		},
+		{
+			"milk\neggs\n",
+			"x milk\ncoffee\n",
+			[]string{"coffee", "eggs", "x milk"},
+		},
+		{
+			"x 2014-1-2 milk\n",
+			"milk 2014-1-5\n",
+			[]string{"x 2014-1-2 milk"},
+		},
+		{
+			"x 2024-1-1 water plants 2024-1-1 rec:1w\nwater plants 2024-1-8 rec:1w\n",
+			"",
+			[]string{"water plants 2024-1-8 rec:1w", "x 2024-1-1 water plants 2024-1-1 rec:1w"},
+		},
+		{
+			"x 2024-1-1 water plants 2024-1-1 rec:1w\nwater plants 2024-1-8 rec:1w\n",
+			"water plants 2024-1-1 rec:1w\n",
+			[]string{"water plants 2024-1-8 rec:1w", "x 2024-1-1 water plants 2024-1-1 rec:1w"},
+		},
+	}
+
+	for _, cas := range cases {
+		a, err := FromReader(strings.NewReader(cas.a))
+		if err != nil {
+			t.Fatalf("unexpected parse error %v", err)
+		}
+		b, err := FromReader(strings.NewReader(cas.b))
+		if err != nil {
+			t.Fatalf("unexpected parse error %v", err)
+		}
+		var got []string
+		for _, task := range a.Merge(b) {
+			got = append(got, task.UnParse())
+		}
+		if !reflect.DeepEqual(got, cas.expect) {
+			t.Errorf("Merging %q and %q got %q (expected %q)", cas.a, cas.b, got, cas.expect)
+		}
+	}
+}
+
+func TestFilterFunc(t *testing.T) {
+	l, err := FromReader(strings.NewReader("a 2014-3-1\nb 2014-3-20\nx c 2014-3-1\nd\ne 2014-3-14\n"))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+
+	defer setNow(time.Date(2014, 3, 15, 9, 0, 0, 0, time.Local))()
+	var got string
+	for _, task := range l.FilterFunc(func(t Task) bool { return t.Overdue() }) {
+		got += task.Title
+	}
+	if got != "ae" {
+		t.Errorf("Got %v, expected ae", got)
+	}
+}
+
+func TestPartition(t *testing.T) {
+	l, err := FromReader(strings.NewReader("a\nx b\nc\nx d\nx e\nf\n"))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+
+	done, pending := l.Partition()
+	if len(done)+len(pending) != len(l) {
+		t.Errorf("Got %v + %v tasks, expected %v", len(done), len(pending), len(l))
+	}
+	var gotDone, gotPending string
+	for _, task := range done {
+		gotDone += task.Title
+	}
+	for _, task := range pending {
+		gotPending += task.Title
+	}
+	if gotDone != "bde" || gotPending != "acf" {
+		t.Errorf("Got %v and %v, expected bde and acf", gotDone, gotPending)
+	}
+}
+
+func TestUnParseOriginal(t *testing.T) {
+	in := "+shop  buy milk\t@store 2014-3-20 pri:2"
+	todo, err := Parse(in)
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+	if got := todo.UnParseOriginal(); got != in {
+		t.Errorf("Got %q, expected %q", got, in)
+	}
+
+	todo.Complete()
+	if got := todo.UnParseOriginal(); got != todo.UnParse() {
+		t.Errorf("Got %q after completing, expected %q", got, todo.UnParse())
+	}
+
+	built := Task{Title: "milk"}
+	if got := built.UnParseOriginal(); got != "milk" {
+		t.Errorf("Got %q for unparsed task, expected milk", got)
+	}
+}
+
+func TestSortByDueDesc(t *testing.T) {
+	in := "b 2014-1-1\nnone\nx done 2014-5-1\na 2014-3-1\nc 2014-1-1\n"
+	asc, err := FromReader(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+	desc, _ := FromReader(strings.NewReader(in))
+
+	sort.Sort(asc)
+	desc.SortByDueDesc()
+
+	cases := []struct {
+		l      TaskList
+		expect string
+	}{
+		{asc, "bcanonedone"},
+		{desc, "abcnonedone"},
+	}
+	for _, cas := range cases {
+		var got string
+		for _, task := range cas.l {
+			got += task.Title
+		}
+		if got != cas.expect {
+			t.Errorf("Got %v, expected %v", got, cas.expect)
+		}
+	}
+}
+
+func TestTitleWordCount(t *testing.T) {
+	cases := []struct {
+		todo   Task
+		expect int
+	}{
+		{Task{}, 0},
+		{Task{Title: "milk"}, 1},
+		{Task{Title: "  buy   some\tmilk  "}, 3},
+		{Task{Title: "call mom", Tags: []string{"family"}, Contexts: []string{"phone"}}, 2},
+	}
+
+	var l TaskList
+	total := 0
+	for _, cas := range cases {
+		if got := cas.todo.TitleWordCount(); got != cas.expect {
+			t.Errorf("On case %q, got %v (expected %v)", cas.todo.Title, got, cas.expect)
+		}
+		l = append(l, cas.todo)
+		total += cas.expect
+	}
+	if got := l.TotalTitleWords(); got != total {
+		t.Errorf("Got %v total words, expected %v", got, total)
+	}
+
+	todo, err := Parse("buy milk 2014-1-2 s:2014-1-1 @store +food")
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+	if got := todo.TitleWordCount(); got != 2 {
+		t.Errorf("Got %v words, expected 2", got)
+	}
+}
+
+func TestMultipleDueDates(t *testing.T) {
+	cases := []struct {
+		in     string
+		expect string
+	}{
+		{"foo 2014-1-2 bar 2014-1-3", "todo: multiple due dates"},
+		{"foo 2014-1-2 today", "todo: multiple due dates"},
+		{"foo 2014-1-2 2014-1-2", "todo: multiple due dates"},
+		{"foo 2014-1-2", ""},
+		{"x 2014-1-2 2014-1-1 foo 2014-1-3", ""},
+	}
+
+	for _, cas := range cases {
+		_, err := Parse(cas.in)
+		switch {
+		case err == nil && cas.expect != "":
+			t.Errorf("On case %v, got no error (expected %v)", cas.in, cas.expect)
+		case err != nil && err.Error() != cas.expect:
+			t.Errorf("On case %v, got %v (expected %v)", cas.in, err, cas.expect)
+		}
+	}
+
+	_, err := FromReader(strings.NewReader("ok\nfoo 2014-1-2 2014-1-3\n"))
+	expect := "todo: multiple due dates on line 2, column 14"
+	if err == nil || err.Error() != expect {
+		t.Errorf("Got %v, expected %v", err, expect)
+	}
+}
+
+func TestParser(t *testing.T) {
+	cases := []struct {
+		layout string
+		in     string
+		expect string
+	}{
+		{"2006/01/02", "foo 2014/03/05 s:2014/03/01", "foo 2014-3-5 s:2014-3-1"},
+		{"02.01.2006", "x 06.03.2014 foo 05.03.2014", "x 2014-3-6 foo 2014-3-5"},
+		{"2006/01/02", "foo 2014-3-5", `foo \2014-3-5`},
+		{"", "foo 2014-3-5", "foo 2014-3-5"},
+	}
+
+	for _, cas := range cases {
+		p := Parser{DateLayout: cas.layout}
+		todo, err := p.Parse(cas.in)
+		if err != nil {
+			t.Errorf("On case %v, unexpected parse error %v", cas.in, err)
+			continue
+		}
+		if got := todo.UnParse(); got != cas.expect {
+			t.Errorf("On case %v, got %v (expected %v)", cas.in, got, cas.expect)
+		}
+	}
+
+	todo, _ := Parser{DateLayout: "2006/01/02"}.Parse("foo 2014-3-5")
+	if !todo.Due.IsZero() || todo.Title != "foo 2014-3-5" {
+		t.Errorf("Got due %v title %v with slash layout", todo.Due, todo.Title)
+	}
+
+	l, err := Parser{DateLayout: "2006/01/02"}.FromReader(strings.NewReader("a 2014/03/05\nb\n"))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+	if len(l) != 2 || l[0].Due.Day() != 5 {
+		t.Errorf("Got %v", l)
+	}
+}
+
+func TestDoneMarkers(t *testing.T) {
+	p := Parser{DoneMarkers: []string{"X", "[x]"}}
+	cases := []struct {
+		in     string
+		done   bool
+		expect string
+	}{
+		{"x foo", true, "x foo"},
+		{"X foo", true, "x foo"},
+		{"[x] (A) 2014-3-6 foo", true, "x (A) 2014-3-6 foo"},
+		{"[X] foo", false, "[X] foo"},
+		{`\X foo`, false, `\X foo`},
+		{`\[x] foo`, false, `\[x] foo`},
+	}
+
+	for _, cas := range cases {
+		todo, err := p.Parse(cas.in)
+		if err != nil {
+			t.Errorf("On case %v, unexpected parse error %v", cas.in, err)
+			continue
+		}
+		if todo.Done != cas.done {
+			t.Errorf("On case %v, got done %v (expected %v)", cas.in, todo.Done, cas.done)
+		}
+		if got := p.UnParse(todo); got != cas.expect {
+			t.Errorf("On case %v, got %v (expected %v)", cas.in, got, cas.expect)
+		}
+	}
+
+	for _, in := range []string{"x buy milk", "X buy milk", "[x] (A) buy milk"} {
+		todo, err := p.Parse(in)
+		if err != nil {
+			t.Fatalf("On case %v, unexpected parse error %v", in, err)
+		}
+		again, err := p.Parse(p.UnParse(todo))
+		if err != nil || !again.Done || again.Title != "buy milk" {
+			t.Errorf("On case %v, %v reparsed to %v, %v", in, p.UnParse(todo), again, err)
+		}
+	}
+
+	for _, in := range []string{"X foo", "[x] foo"} {
+		if todo, err := Parse(in); err != nil || todo.Done {
+			t.Errorf("On case %v, got %v, %v with the default markers", in, todo, err)
+		}
+	}
+}
+
+func TestSigils(t *testing.T) {
+	p := Parser{TagSigil: '#', ContextSigil: '%'}
+	cases := []struct {
+		in       string
+		title    string
+		tags     []string
+		contexts []string
+		expect   string
+	}{
+		{"foo #work %home", "foo", []string{"work"}, []string{"home"}, "foo %home #work"},
+		{"foo +bar @baz", "foo +bar @baz", nil, nil, "foo +bar @baz"},
+		{`foo \#bar`, "foo #bar", nil, nil, `foo \#bar`},
+		{"#work foo", "foo", []string{"work"}, nil, "foo #work"},
+	}
+
+	for _, cas := range cases {
+		todo, err := p.Parse(cas.in)
+		if err != nil {
+			t.Errorf("On case %v, unexpected parse error %v", cas.in, err)
+			continue
+		}
+		if todo.Title != cas.title || !equalStrings(todo.Tags, cas.tags) || !equalStrings(todo.Contexts, cas.contexts) {
+			t.Errorf("On case %v, got %v %v %v", cas.in, todo.Title, todo.Tags, todo.Contexts)
+		}
+		if got := p.UnParse(todo); got != cas.expect {
+			t.Errorf("On case %v, got %v (expected %v)", cas.in, got, cas.expect)
+		}
+	}
+
+	l, err := p.FromReader(strings.NewReader("#work a\n\nb\n"))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+	if len(l) != 2 || !equalStrings(l[0].Tags, []string{"work"}) {
+		t.Errorf("Got %v", l)
+	}
+}
+
+func TestSplitTagCommas(t *testing.T) {
+	cases := []struct {
+		split    bool
+		in       string
+		title    string
+		tags     []string
+		contexts []string
+		expect   string
+	}{
+		{true, "foo +a,b,c @x,y", "foo", []string{"a", "b", "c"}, []string{"x", "y"}, "foo @x @y +a +b +c"},
+		{true, "foo +a,,b, +c", "foo", []string{"a", "b", "c"}, nil, "foo +a +b +c"},
+		{true, "foo +, @,", "foo +, @,", nil, nil, `foo \+, \@,`},
+		{false, "foo +a,b,c @x,y", "foo", []string{"a,b,c"}, []string{"x,y"}, "foo @x,y +a,b,c"},
+	}
+
+	for _, cas := range cases {
+		p := Parser{SplitTagCommas: cas.split}
+		todo, err := p.Parse(cas.in)
+		if err != nil {
+			t.Errorf("On case %v, unexpected parse error %v", cas.in, err)
+			continue
+		}
+		if todo.Title != cas.title || !equalStrings(todo.Tags, cas.tags) || !equalStrings(todo.Contexts, cas.contexts) {
+			t.Errorf("On case %v, got %v %v %v", cas.in, todo.Title, todo.Tags, todo.Contexts)
+		}
+		if got := todo.UnParse(); got != cas.expect {
+			t.Errorf("On case %v, got %v (expected %v)", cas.in, got, cas.expect)
+		}
+	}
+
+	todo, _ := Parser{SplitTagCommas: true}.Parse("+a,b foo @x")
+	if got, expect := todo.UnParseOrdered(), "+a +b foo @x"; got != expect {
+		t.Errorf("Got %v (expected %v)", got, expect)
+	}
+	if _, err := (Parser{SplitTagCommas: true, Strict: true}).Parse("foo +,"); err == nil {
+		t.Errorf("Expected an error for an empty tag in strict mode")
+	}
+}
+
+func TestClone(t *testing.T) {
+	orig, err := Parse("foo @home +a +b id:1")
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+	orig.Tags = orig.Tags[:1] // leave spare capacity for append to reuse
+
+	c := orig.Clone()
+	c.Tags = append(c.Tags, "c")
+	c.Contexts[0] = "work"
+	c.Meta["id"] = "2"
+
+	if got := orig.UnParse(); got != "foo @home +a id:1" {
+		t.Errorf("Original changed to %v", got)
+	}
+	if spare := orig.Tags[:2][1]; spare != "b" {
+		t.Errorf("Appending to clone overwrote original tag b with %v", spare)
+	}
+	if got := c.UnParse(); got != "foo @work +a +c id:2" {
+		t.Errorf("Got clone %v, expected %v", got, "foo @work +a +c id:2")
+	}
+}
+
+func TestID(t *testing.T) {
+	l, err := FromReader(strings.NewReader("milk @store +a +b\neggs\nmilk  @store +a +b\nmilks @store +a +b\n"))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+
+	if id := l[0].ID(); len(id) != 8 {
+		t.Errorf("Got ID %q, expected 8 hex digits", id)
+	}
+	if l[0].ID() != l[2].ID() {
+		t.Errorf("Got different IDs %v and %v for equal tasks", l[0].ID(), l[2].ID())
+	}
+	if l[0].ID() == l[3].ID() {
+		t.Errorf("Got the same ID %v for different titles", l[0].ID())
+	}
+	if l[0].ID() == l[1].ID() {
+		t.Errorf("Got the same ID %v for different tasks", l[0].ID())
+	}
+}
+
+func TestWithDue(t *testing.T) {
+	l, err := FromReader(strings.NewReader("a 2014-1-1\nb s:2014-1-1\nc\nx d 2014-1-1\n"))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+
+	cases := []struct {
+		l      TaskList
+		expect string
+	}{
+		{l.WithDue(), "ad"},
+		{l.WithoutDue(), "bc"},
+	}
+	for _, cas := range cases {
+		var got string
+		for _, task := range cas.l {
+			got += task.Title
+		}
+		if got != cas.expect {
+			t.Errorf("Got %v, expected %v", got, cas.expect)
+		}
+	}
+}
+
+func TestArchive(t *testing.T) {
+	l, err := FromReader(strings.NewReader("a\nx b\nc @home\nx 2014-1-2 d\n"))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+
+	var done, pending bytes.Buffer
+	if err := l.Archive(&done, &pending); err != nil {
+		t.Fatalf("unexpected write error %v", err)
+	}
+	if got, expect := done.String(), "x b\nx 2014-1-2 d\n"; got != expect {
+		t.Errorf("Got done %q, expected %q", got, expect)
+	}
+	if got, expect := pending.String(), "a\nc @home\n"; got != expect {
+		t.Errorf("Got pending %q, expected %q", got, expect)
+	}
+
+	done.Reset()
+	if err := l.Archive(&done, nil); err != nil {
+		t.Fatalf("unexpected write error %v", err)
+	}
+	if got, expect := done.String(), "x b\nx 2014-1-2 d\n"; got != expect {
+		t.Errorf("Got done %q, expected %q", got, expect)
+	}
+	if err := l.Archive(nil, nil); err != nil {
+		t.Errorf("unexpected error %v with nil writers", err)
+	}
+}
+
+func TestNormalizeTagsContexts(t *testing.T) {
+	in := "foo @Home @home @Work +Urgent +urgent +URGENT +later"
+	todo, err := Parser{LowercaseTagsContexts: true}.Parse(in)
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+	expect := "foo @home @work +urgent +later"
+	if got := todo.UnParse(); got != expect {
+		t.Errorf("Got %v, expected %v", got, expect)
+	}
+	if got := todo.UnParseOriginal(); got != expect {
+		t.Errorf("Got original %v, expected %v", got, expect)
+	}
+
+	todo, err = Parse(in)
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+	if got := todo.UnParse(); got != "foo @Home @home @Work +Urgent +urgent +URGENT +later" {
+		t.Errorf("Default parser normalized to %v", got)
+	}
+
+	todo, _ = Parse("foo @home +a")
+	todo.NormalizeTagsContexts()
+	if todo.dirty {
+		t.Errorf("Normalizing an already normal task marked it changed")
+	}
+}
+
+func TestReplaceInTitle(t *testing.T) {
+	l, err := FromReader(strings.NewReader("write foo docs +foo\nfix foo and foo\nunrelated @foo\n"))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+
+	if n := l.ReplaceInTitle("foo", "bar"); n != 2 {
+		t.Errorf("Got %v tasks changed, expected 2", n)
+	}
+	expect := []string{"write bar docs +foo", "fix bar and bar", "unrelated @foo"}
+	for i := range l {
+		if got := l[i].UnParseOriginal(); got != expect[i] {
+			t.Errorf("Got %v, expected %v", got, expect[i])
+		}
+	}
+	if l[2].dirty {
+		t.Errorf("Unaffected task was marked changed")
+	}
+	if n := l.ReplaceInTitle("", "x"); n != 0 {
+		t.Errorf("Got %v tasks changed replacing the empty string", n)
+	}
+}
+
+func TestRename(t *testing.T) {
+	in := "a +proj +x @home\nb +x +proj +new @home @office\nc +new @work\n"
+	l, err := FromReader(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+
+	if n := l.RenameTag("proj", "new"); n != 2 {
+		t.Errorf("Got %v tasks changed, expected 2", n)
+	}
+	if n := l.RenameContext("home", "office"); n != 2 {
+		t.Errorf("Got %v tasks changed, expected 2", n)
+	}
+	if n := l.RenameTag("missing", "new"); n != 0 {
+		t.Errorf("Got %v tasks changed renaming a missing tag", n)
+	}
+
+	expect := []string{"a @office +new +x", "b @office +x +new", "c @work +new"}
+	for i := range l {
+		if got := l[i].UnParse(); got != expect[i] {
+			t.Errorf("Got %v, expected %v", got, expect[i])
+		}
+	}
+	if l[2].dirty {
+		t.Errorf("Unaffected task was marked changed")
+	}
+}
+
+func TestAddRemoveTag(t *testing.T) {
+	l, err := FromReader(strings.NewReader("a +x +y\nb +y\nc\nd +x +y +x\n"))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+
+	filtered := l.Filter("+y")
+	if n := filtered.AddTag("x"); n != 1 {
+		t.Errorf("AddTag changed %v tasks (expected 1)", n)
+	}
+	if got, expect := filtered[1].UnParseOriginal(), "b +y +x"; got != expect {
+		t.Errorf("Got %v (expected %v)", got, expect)
+	}
+	if got, expect := l[1].UnParseOriginal(), "b +y"; got != expect {
+		t.Errorf("Adding to the filtered list changed the original to %v", got)
+	}
+	if l[0].dirty || filtered[0].dirty {
+		t.Errorf("AddTag marked a task that already had the tag changed")
+	}
+	if n := l.AddTag(""); n != 0 || l[2].dirty || len(l[2].Tags) != 0 {
+		t.Errorf("AddTag changed %v tasks for an empty tag", n)
+	}
+
+	if n := l.RemoveTag("x"); n != 2 {
+		t.Errorf("RemoveTag changed %v tasks (expected 2)", n)
+	}
+	if n := l.RemoveTag("absent"); n != 0 {
+		t.Errorf("RemoveTag changed %v tasks for an absent tag", n)
+	}
+	expect := []string{"a +y", "b +y", "c", "d +y"}
+	for i := range l {
+		if got := l[i].UnParseOriginal(); got != expect[i] {
+			t.Errorf("On case %v, got %v (expected %v)", i, got, expect[i])
+		}
+	}
+	if l[2].dirty {
+		t.Errorf("RemoveTag marked an untagged task changed")
+	}
+	if got := filtered[0].UnParse(); got != "a +x +y" {
+		t.Errorf("Removing from the original changed the filtered list to %v", got)
+	}
+}
+
+func TestSorted(t *testing.T) {
+	l, err := FromReader(strings.NewReader("c\nx a\nb 2014-1-1\n"))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+
+	sorted := l.Sorted()
+	var got, orig string
+	for i := range l {
+		got += sorted[i].Title
+		orig += l[i].Title
+	}
+	if got != "bca" {
+		t.Errorf("Got sorted %v, expected bca", got)
+	}
+	if orig != "cab" {
+		t.Errorf("Original reordered to %v", orig)
+	}
+}
+
+func TestSortBy(t *testing.T) {
+	l, err := FromReader(strings.NewReader("three\na\nbb\ncc\nfour\n"))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+
+	byLen := l.SortBy(func(a, b Task) bool { return len(a.Title) < len(b.Title) })
+	var got []string
+	for _, todo := range byLen {
+		got = append(got, todo.Title)
+	}
+	if expect := []string{"a", "bb", "cc", "four", "three"}; !equalStrings(got, expect) {
+		t.Errorf("Got %v (expected %v)", got, expect)
+	}
+	if l[0].Title != "three" {
+		t.Errorf("SortBy changed the list to %v", l)
+	}
+}
+
+func TestReverse(t *testing.T) {
+	titles := func(l TaskList) (s string) {
+		for _, task := range l {
+			s += task.Title
+		}
+		return s
+	}
+	l, err := FromReader(strings.NewReader("c\na\nd\nb\n"))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+
+	r := l.Reversed()
+	if got := titles(r); got != "bdac" {
+		t.Errorf("Got %v reversed (expected bdac)", got)
+	}
+	if got := titles(l); got != "cadb" {
+		t.Errorf("Reversed changed the list to %v", got)
+	}
+
+	l.Reverse()
+	if got := titles(l); got != "bdac" {
+		t.Errorf("Got %v after Reverse (expected bdac)", got)
+	}
+	r[0].Title = "z"
+	if l[0].Title != "b" {
+		t.Errorf("Reversed shares its tasks with the list")
+	}
+
+	for _, in := range []TaskList{nil, {{Title: "a"}}} {
+		in.Reverse()
+		if got := in.Reversed(); len(got) != len(in) {
+			t.Errorf("Got %v reversing %v", got, in)
+		}
+	}
+}
+
+func TestEqual(t *testing.T) {
+	cases := []struct {
+		a, b   string
+		expect bool
+	}{
+		{"foo +a +b @x @y", "foo @y +b @x +a", true},
+		{"foo +a +a", "foo +a", true},
+		{"foo 2014-1-1 s:2013-12-1", "foo s:2013-12-1 2014-1-1", true},
+		{"foo +a", "bar +a", false},
+		{"foo +a", "foo +a +b", false},
+		{"foo @a", "foo +a", false},
+		{"x foo", "foo", false},
+		{"foo 2014-1-1", "foo 2014-1-2", false},
+		{"foo s:2014-1-1", "foo", false},
+	}
+
+	var as, bs TaskList
+	for _, cas := range cases {
+		a, err := Parse(cas.a)
+		if err != nil {
+			t.Fatalf("unexpected parse error %v", err)
+		}
+		b, err := Parse(cas.b)
+		if err != nil {
+			t.Fatalf("unexpected parse error %v", err)
+		}
+		if got := a.Equal(b); got != cas.expect {
+			t.Errorf("Comparing %v and %v, got %v (expected %v)", cas.a, cas.b, got, cas.expect)
+		}
+		if cas.expect {
+			as = append(as, a)
+			bs = append(bs, b)
+		}
+	}
+
+	if !as.Equal(bs) {
+		t.Errorf("Lists of equal tasks were not equal")
+	}
+	if as.Equal(bs[1:]) {
+		t.Errorf("Lists of different lengths were equal")
+	}
+	bs[0], bs[1] = bs[1], bs[0]
+	if as.Equal(bs) {
+		t.Errorf("Reordered lists were equal")
+	}
+}
+
+func TestEachTask(t *testing.T) {
+	in := "a\nb\nc\nx\nd\n"
+	stop := errors.New("stop")
+
+	var got string
+	err := EachTask(strings.NewReader(in), func(task Task) error {
+		got += task.Title
+		if task.Title == "b" {
+			return stop
+		}
+		return nil
+	})
+	if err != stop {
+		t.Errorf("Got %v, expected %v", err, stop)
+	}
+	if got != "ab" {
+		t.Errorf("Got %v before stopping, expected ab", got)
+	}
+
+	got = ""
+	err = EachTask(strings.NewReader(in), func(task Task) error {
+		got += task.Title
+		return nil
+	})
+	if perr, ok := err.(*ParseError); !ok || perr.Line != 4 {
+		t.Errorf("Got %v, expected a parse error on line 4", err)
+	}
+	if got != "abc" {
+		t.Errorf("Got %v before the parse error, expected abc", got)
+	}
+}
+
+func TestEmptyTitle(t *testing.T) {
+	cases := []string{
+		"x 2014-1-2 @home",
+		"2014-1-2 +tag",
+		"(A) @home",
+		"x (B) 2014-1-2 2014-1-1 s:2013-12-1 @home id:4",
+		"x @home 2014-1-1",
+		"x 2014-1-2 @home 2014-1-5",
+		"x 2014-1-2 2014-1-1 @home +tag 2014-1-5",
+	}
+
+	for _, in := range cases {
+		todo, err := Parse(in)
+		if err != nil {
+			t.Errorf("On case %v, unexpected parse error %v", in, err)
+			continue
+		}
+		if todo.HasTitle() {
+			t.Errorf("On case %v, got title %q", in, todo.Title)
+		}
+		if got := todo.UnParse(); got != in {
+			t.Errorf("On case %v, unparsed to %q", in, got)
+		}
+	}
+
+	defer setNow(time.Date(2014, 3, 5, 12, 0, 0, 0, time.Local))()
+	completed := []struct {
+		in, expect, ordered string
+	}{
+		{"@home 2014-1-5", "x 2014-3-5 @home 2014-1-5", "x 2014-3-5 @home 2014-1-5"},
+		{"2014-1-5", "x 2014-3-5 due:2014-1-5", "x 2014-3-5 due:2014-1-5"},
+		{"2014-1-5 id:4", "x 2014-3-5 id:4 2014-1-5", "x 2014-3-5 id:4 2014-1-5"},
+	}
+	for _, cas := range completed {
+		todo, err := Parse(cas.in)
+		if err != nil {
+			t.Fatalf("On case %v, unexpected parse error %v", cas.in, err)
+		}
+		todo.Complete()
+		if got := todo.UnParse(); got != cas.expect {
+			t.Errorf("On case %v, got %v (expected %v)", cas.in, got, cas.expect)
+		}
+		for _, out := range []string{todo.UnParse(), todo.UnParseOrdered()} {
+			again, err := Parse(out)
+			if err != nil || !again.Due.Equal(todo.Due) || !again.Completed.Equal(todo.Completed) || !again.Created.IsZero() || len(again.Meta) != len(todo.Meta) {
+				t.Errorf("On case %v, completing gave %v, which reparsed to %v, %v", cas.in, out, again, err)
+			}
+		}
+		if got := todo.UnParseOrdered(); got != cas.ordered {
+			t.Errorf("On case %v, got %v ordered after completing (expected %v)", cas.in, got, cas.ordered)
+		}
+	}
+
+	blank := Task{Title: "  ", Contexts: []string{"home"}}
+	if blank.HasTitle() {
+		t.Errorf("Whitespace title counted as a title")
+	}
+	if got := blank.UnParse(); got != "@home" {
+		t.Errorf("Got %q, expected %q", got, "@home")
+	}
+	if !(Task{Title: "a"}).HasTitle() {
+		t.Errorf("Title a did not count as a title")
+	}
+}
+
+func TestLine(t *testing.T) {
+	l, err := FromReader(strings.NewReader("a\nb\nc\n"))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+	l[0], l[1] = l[1], l[0]
+
+	task, ok := l.Line(2)
+	if !ok || task.Title != "b" {
+		t.Fatalf("Got %v, %v for line 2, expected b", task, ok)
+	}
+	task.Complete()
+	if !l[0].Done {
+		t.Errorf("Changing the task from Line did not change the list")
+	}
+
+	for _, n := range []int{0, 4, -1} {
+		if task, ok := l.Line(n); ok {
+			t.Errorf("Got %v for line %v, expected none", task, n)
+		}
+	}
+}
+
+func TestPage(t *testing.T) {
+	l, err := FromReader(strings.NewReader("a\nb\nc\nd\ne\n"))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+
+	cases := []struct {
+		offset, limit int
+		expect        string
+	}{
+		{0, 2, "ab"},
+		{2, 2, "cd"},
+		{4, 2, "e"},
+		{5, 2, ""},
+		{9, 1, ""},
+		{-1, 2, ""},
+		{1, -1, "bcde"},
+		{0, 0, ""},
+	}
+
+	for _, cas := range cases {
+		var got string
+		for _, task := range l.Page(cas.offset, cas.limit) {
+			got += task.Title
+		}
+		if got != cas.expect {
+			t.Errorf("On case %v %v, got %v (expected %v)", cas.offset, cas.limit, got, cas.expect)
+		}
+	}
+
+	page := l.Page(0, 2)
+	page[0].Title = "changed"
+	page = append(page, Task{Title: "appended"})
+	if l[0].Title != "a" || l[2].Title != "c" {
+		t.Errorf("Changing the page changed the list: %v", l)
+	}
+}
+
+func TestReindex(t *testing.T) {
+	l, err := FromReader(strings.NewReader("a +x\nb\nc +x\nd\ne +x\n"))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+
+	filtered := l.Filter("+x")
+	re := filtered.Reindexed()
+	for i, expect := range []int{1, 3, 5} {
+		if got := filtered[i].index; got != expect {
+			t.Errorf("Reindexed changed index %v to %v (expected %v)", i, got, expect)
+		}
+	}
+	for i := range re {
+		if re[i].index != i+1 {
+			t.Errorf("On case %v, got index %v (expected %v)", re[i].Title, re[i].index, i+1)
+		}
+	}
+
+	filtered.Reindex()
+	for i := range filtered {
+		if filtered[i].index != i+1 {
+			t.Errorf("On case %v, got index %v (expected %v)", filtered[i].Title, filtered[i].index, i+1)
+		}
+	}
+	if task, ok := filtered.Line(2); !ok || task.Title != "c" {
+		t.Errorf("Got %v, %v for line 2, expected c", task, ok)
+	}
+}
+
+func TestBytes(t *testing.T) {
+	in := "(A) foo 2014-3-5 @home +work\nx 2014-3-6 bar\nbaz s:2014-3-1\n"
+	l, err := FromBytes([]byte(in))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+	if len(l) != 3 || l[0].Priority != 'A' || !l[1].Done {
+		t.Errorf("Got %v", l)
+	}
+
+	out, err := l.Bytes()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if string(out) != in {
+		t.Errorf("Got %q (expected %q)", out, in)
+	}
+	again, err := FromBytes(out)
+	if err != nil || !again.Equal(l) {
+		t.Errorf("Got %v, %v after round trip (expected %v)", again, err, l)
+	}
+
+	if _, err := FromBytes([]byte("a\nx\n")); err == nil {
+		t.Errorf("Expected a parse error")
+	}
+	if out, err := TaskList(nil).Bytes(); err != nil || len(out) != 0 {
+		t.Errorf("Got %q, %v for an empty list", out, err)
+	}
+}
+
+func TestMatchesFuzzy(t *testing.T) {
+	cases := []struct {
+		title   string
+		query   string
+		maxDist int
+		expect  bool
+	}{
+		{"buy milk at the store", "milk", 0, true},
+		{"buy milk at the store", "mlik", 0, false},
+		{"buy milk at the store", "milc", 1, true},
+		{"buy milk at the store", "milc", 0, false},
+		{"buy milk at the store", "stre", 1, true},
+		{"buy milk at the store", "sttore", 1, true},
+		{"buy milk at the store", "bread", 2, false},
+		{"café au lait", "cafe", 1, true},
+		{"café au lait", "cafe", 0, false},
+		{"", "a", 1, true},
+		{"", "ab", 1, false},
+		{"anything", "", 0, true},
+	}
+
+	for _, cas := range cases {
+		todo := Task{Title: cas.title}
+		if got := todo.MatchesFuzzy(cas.query, cas.maxDist); got != cas.expect {
+			t.Errorf("On case %v %v %v, got %v (expected %v)", cas.title, cas.query, cas.maxDist, got, cas.expect)
+		}
+	}
+
+	l := TaskList{{Title: "call mom"}, {Title: "cell phone"}, {Title: "email"}}
+	var got []string
+	for _, todo := range l.FilterFuzzy("call", 1) {
+		got = append(got, todo.Title)
+	}
+	if expect := []string{"call mom", "cell phone"}; !equalStrings(got, expect) {
+		t.Errorf("Got %v (expected %v)", got, expect)
+	}
+}
+
+func TestTitleOnly(t *testing.T) {
+	cases := []struct {
+		in     string
+		expect string
+	}{
+		{"(A) buy milk 2014-3-5 @store +errand", "buy milk"},
+		{`buy \+milk \2014-3-5 @`, "buy"},
+		{`\(B) call + mom s:2014-3-1`, "call mom"},
+		{"x 2014-3-6 done", "done"},
+	}
+
+	for _, cas := range cases {
+		todo, err := Parse(cas.in)
+		if err != nil {
+			t.Errorf("On case %v, unexpected parse error %v", cas.in, err)
+			continue
+		}
+		if got := todo.TitleOnly(); got != cas.expect {
+			t.Errorf("On case %v, got %q (expected %q)", cas.in, got, cas.expect)
+		}
+	}
+
+	todo := Task{Title: "  write   the\treport  @ today "}
+	if got, expect := todo.TitleOnly(), "write the report"; got != expect {
+		t.Errorf("Got %q (expected %q)", got, expect)
+	}
+}
+
+func TestNotes(t *testing.T) {
+	in := "  first\nfoo @home\n  bar baz  \n\tqux\n# comment\nnext\n"
+	l, err := FromReader(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+	if len(l) != 3 {
+		t.Fatalf("Got %v tasks (expected 3)", len(l))
+	}
+	if l[0].Title != "first" || len(l[0].Notes) != 0 {
+		t.Errorf("Got %v %v for an indented first line", l[0].Title, l[0].Notes)
+	}
+	if expect := []string{"bar baz", "qux"}; !equalStrings(l[1].Notes, expect) {
+		t.Errorf("Got notes %v (expected %v)", l[1].Notes, expect)
+	}
+	if l[1].index != 2 || l[2].index != 6 || len(l[2].Notes) != 0 {
+		t.Errorf("Got %v", l)
+	}
+
+	expect := "foo @home\n  bar baz\n  qux"
+	if got := l[1].UnParse(); got != expect {
+		t.Errorf("Got %q (expected %q)", got, expect)
+	}
+	if got := l[1].UnParseOriginal(); got != expect {
+		t.Errorf("Got original %q (expected %q)", got, expect)
+	}
+	out, err := l.Bytes()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	again, err := FromBytes(out)
+	if err != nil || len(again) != 3 || !equalStrings(again[1].Notes, l[1].Notes) {
+		t.Errorf("Got %v, %v after round trip", again, err)
+	}
+
+	all, errs := FromReaderAll(strings.NewReader("foo\n  note\nx\n  orphan\n"))
+	if len(errs) != 1 || len(all) != 2 || !equalStrings(all[0].Notes, []string{"note"}) || all[1].Title != "orphan" {
+		t.Errorf("Got %v, %v from FromReaderAll", all, errs)
+	}
+}
+
+func TestFlagged(t *testing.T) {
+	cases := []struct {
+		in      string
+		flagged bool
+		title   string
+		unparse string
+	}{
+		{"* foo", true, "foo", "* foo"},
+		{"(B) * call mom", true, "call mom", "(B) * call mom"},
+		{"x (A) 2014-3-6 2014-3-1 * foo +a", true, "foo", "x (A) 2014-3-6 2014-3-1 * foo +a"},
+		{"x * foo", true, "foo", "x * foo"},
+		{"foo * bar", false, "foo * bar", "foo * bar"},
+		{"*", false, "*", `\*`},
+		{`\* foo`, false, "* foo", `\* foo`},
+		{"* * foo", true, "* foo", `* \* foo`},
+	}
+
+	for _, cas := range cases {
+		todo, err := Parse(cas.in)
+		if err != nil {
+			t.Errorf("On case %v, unexpected parse error %v", cas.in, err)
+			continue
+		}
+		if todo.Flagged != cas.flagged || todo.Title != cas.title {
+			t.Errorf("On case %v, got flagged %v title %v (expected %v %v)", cas.in, todo.Flagged, todo.Title, cas.flagged, cas.title)
+		}
+		got := todo.UnParse()
+		if got != cas.unparse {
+			t.Errorf("On case %v, got %v (expected %v)", cas.in, got, cas.unparse)
+		}
+		if again, err := Parse(got); err != nil || again.Flagged != todo.Flagged || again.Title != todo.Title {
+			t.Errorf("On case %v, reparsed to %v, %v", cas.in, again, err)
+		}
+	}
+
+	defer setNow(time.Date(2014, 3, 5, 12, 0, 0, 0, time.Local))()
+	todo, _ := Parse("* foo")
+	todo.Complete()
+	if got, expect := todo.UnParse(), "x 2014-3-5 * foo"; got != expect {
+		t.Errorf("Got %v after completing (expected %v)", got, expect)
+	}
+}
+
+func TestPriorityWeight(t *testing.T) {
+	cases := []struct {
+		in     string
+		expect int
+	}{
+		{"(A) foo", 26},
+		{"(B) foo", 25},
+		{"(Z) foo", 1},
+		{"foo pri:M", 14},
+		{"foo", 0},
+	}
+
+	for _, cas := range cases {
+		todo, err := Parse(cas.in)
+		if err != nil {
+			t.Errorf("On case %v, unexpected parse error %v", cas.in, err)
+			continue
+		}
+		if got := todo.PriorityWeight(); got != cas.expect {
+			t.Errorf("On case %v, got %v (expected %v)", cas.in, got, cas.expect)
+		}
+	}
+}
+
+func TestHas(t *testing.T) {
+	a, err := Parse("foo 2014-3-5 @home +work")
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+	b, err := Parse("bar s:2014-3-1")
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+
+	cases := []struct {
+		got, expect bool
+	}{
+		{a.HasTag("work"), true},
+		{a.HasTag("home"), false},
+		{a.HasTag(""), false},
+		{a.HasContext("home"), true},
+		{a.HasContext("work"), false},
+		{a.HasContext(""), false},
+		{a.HasDue(), true},
+		{a.HasStart(), false},
+		{b.HasTag("work"), false},
+		{b.HasDue(), false},
+		{b.HasStart(), true},
+		{Task{Tags: []string{""}}.HasTag(""), false},
+	}
+
+	for i, cas := range cases {
+		if cas.got != cas.expect {
+			t.Errorf("On case %v, got %v (expected %v)", i, cas.got, cas.expect)
+		}
+	}
+}
+
+func TestPriorityKey(t *testing.T) {
+	cases := []struct {
+		in       string
+		priority byte
+		unparse  string
+	}{
+		{"foo pri:B", 'B', "(B) foo"},
+		{"(A) foo pri:B", 'A', "(A) foo"},
+		{"foo pri:C pri:D", 'C', "(C) foo"},
+		{"x foo pri:B", 'B', "x (B) foo"},
+		{"foo pri:b", 0, "foo pri:b"},
+		{"foo pri:2", 0, "foo pri:2"},
+	}
+
+	for _, cas := range cases {
+		todo, err := Parse(cas.in)
+		if err != nil {
+			t.Errorf("On case %v, unexpected parse error %v", cas.in, err)
+			continue
+		}
+		if todo.Priority != cas.priority {
+			t.Errorf("On case %v, got priority %q (expected %q)", cas.in, todo.Priority, cas.priority)
+		}
+		if got := todo.UnParse(); got != cas.unparse {
+			t.Errorf("On case %v, unparsed to %v (expected %v)", cas.in, got, cas.unparse)
+		}
+	}
+
+	for _, in := range []string{"pri:A", "x pri:A", "x 2014-1-1 pri:A pri:B", "* pri:A"} {
+		_, err := Parse(in)
+		if err == nil || !strings.Contains(err.Error(), "only priority") {
+			t.Errorf("On case %v, got %v (expected an only priority error)", in, err)
+		}
+	}
+}
+
+func TestCompletedBetween(t *testing.T) {
+	in := "x 2014-3-12 c\nx 2014-3-9 a\nx 2014-3-10 b\nx undated\nx 2014-3-16 d\nx 2014-3-15 e\npending 2014-3-11\n"
+	l, err := FromReader(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+	date := func(d int) time.Time { return time.Date(2014, 3, d, 0, 0, 0, 0, time.Local) }
+
+	cases := []struct {
+		from, to time.Time
+		expect   string
+	}{
+		{date(10), date(15), "bce"},
+		{date(11), date(14), "c"},
+		{time.Time{}, date(10), "ab"},
+		{date(15), time.Time{}, "ed"},
+		{time.Time{}, time.Time{}, "abced"},
+	}
+
+	for _, cas := range cases {
+		var got string
+		for _, task := range l.CompletedBetween(cas.from, cas.to) {
+			got += task.Title
+		}
+		if got != cas.expect {
+			t.Errorf("On range %v-%v, got %v (expected %v)", cas.from, cas.to, got, cas.expect)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a, err := FromReader(strings.NewReader("milk\neggs @store\ncall mom\ndup\ndup\n"))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+	b, err := FromReader(strings.NewReader("call mom\nbuy eggs @store\nmilk\ncoffee\ndup\n"))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+
+	titles := func(l TaskList) string {
+		var s []string
+		for _, task := range l {
+			s = append(s, task.Title)
+		}
+		return strings.Join(s, "|")
+	}
+
+	added, removed, unchanged := a.Diff(b)
+	if got := titles(added); got != "buy eggs|coffee" {
+		t.Errorf("Got added %v", got)
+	}
+	if got := titles(removed); got != "eggs|dup" {
+		t.Errorf("Got removed %v", got)
+	}
+	if got := titles(unchanged); got != "milk|call mom|dup" {
+		t.Errorf("Got unchanged %v", got)
+	}
+}
+
+func TestBOMAndCRLF(t *testing.T) {
+	in := "\uFEFF(A) call mom @phone\r\nbuy milk +food\r\nx done\r"
+	l, err := FromReader(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+
+	expect := []string{"(A) call mom @phone", "buy milk +food", "x done"}
+	if len(l) != len(expect) {
+		t.Fatalf("Got %v tasks, expected %v", len(l), len(expect))
+	}
+	for i := range l {
+		if l[i].Raw != expect[i] || l[i].UnParse() != expect[i] {
+			t.Errorf("Got raw %q unparsed %q, expected %q", l[i].Raw, l[i].UnParse(), expect[i])
+		}
+	}
+	if l[0].Priority != 'A' || l[1].Tags[0] != "food" {
+		t.Errorf("Got priority %q and tag %q", l[0].Priority, l[1].Tags[0])
+	}
+
+	l, errs := FromReaderAll(strings.NewReader(in))
+	if len(errs) != 0 || len(l) != 3 || l[0].Priority != 'A' {
+		t.Errorf("FromReaderAll got %v, %v", l, errs)
+	}
+}
+
+func TestComments(t *testing.T) {
+	in := "# groceries\nmilk\n\n  # hardware\n   \nnails\n\\#1 priority\nx\n"
+	_, err := FromReader(strings.NewReader(in))
+	if perr, ok := err.(*ParseError); !ok || perr.Line != 8 {
+		t.Errorf("Got %v, expected a parse error on line 8", err)
+	}
+
+	l, errs := FromReaderAll(strings.NewReader(in))
+	if len(errs) != 1 {
+		t.Errorf("Got errors %v, expected one", errs)
+	}
+	expect := []struct {
+		line  int
+		title string
+	}{{2, "milk"}, {6, "nails"}, {7, "#1 priority"}}
+	if len(l) != len(expect) {
+		t.Fatalf("Got %v tasks, expected %v", len(l), len(expect))
+	}
+	for i := range expect {
+		if l[i].index != expect[i].line || l[i].Title != expect[i].title {
+			t.Errorf("Got %v on line %v, expected %v on line %v", l[i].Title, l[i].index, expect[i].title, expect[i].line)
+		}
+	}
+	if got := l[2].UnParse(); got != "\\#1 priority" {
+		t.Errorf("Got %v, expected the # to be escaped", got)
+	}
+
+	_, errs = Parser{KeepComments: true}.FromReaderAll(strings.NewReader(in))
+	if len(errs) != 3 {
+		t.Errorf("Got errors %v keeping comments, expected 3", errs)
+	}
+}
+
+func TestFromReaderStats(t *testing.T) {
+	in := "# work\nwrite report\n  a note\n\n   \n# home\nbuy milk\nx call mom\n"
+	l, st, err := FromReaderStats(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+	if expect := (ParseStats{Tasks: 3, Blank: 2, Comments: 2, Total: 8}); st != expect {
+		t.Errorf("Got %+v (expected %+v)", st, expect)
+	}
+	if len(l) != 3 || len(l[0].Notes) != 1 {
+		t.Errorf("Got %v", l)
+	}
+
+	_, st, err = Parser{KeepComments: true}.FromReaderStats(strings.NewReader("a\n# b\n"))
+	if err != nil || st != (ParseStats{Tasks: 2, Total: 2}) {
+		t.Errorf("Got %+v, %v keeping comments", st, err)
+	}
+
+	_, st, err = FromReaderStats(strings.NewReader("a\n\nx\nb\n"))
+	if err == nil || st != (ParseStats{Tasks: 1, Blank: 1, Total: 3}) {
+		t.Errorf("Got %+v, %v for a bad line", st, err)
+	}
+}
+
+func TestProjectProgress(t *testing.T) {
+	in := "x design +app\nbuild +app\nx test +app +qa\nx unrelated\nship +app\n"
+	l, err := FromReader(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+
+	cases := []struct {
+		tag         string
+		done, total int
+	}{
+		{"app", 2, 4},
+		{"qa", 1, 1},
+		{"none", 0, 0},
+	}
+	for _, cas := range cases {
+		done, total := l.ProjectProgress(cas.tag)
+		if done != cas.done || total != cas.total {
+			t.Errorf("On tag %v, got %v/%v (expected %v/%v)", cas.tag, done, total, cas.done, cas.total)
+		}
+	}
+}
+
+func TestNextActions(t *testing.T) {
+	l, err := FromReader(strings.NewReader(`c 2014-3-5 +a
+b 2014-3-1 +a +b
+x z 2014-1-1 +a
+y +b
+x done +c
+a 2014-3-1 +b
+w +d
+v +d
+`))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+
+	got := l.NextActions()
+	expect := map[string]string{"a": "b", "b": "a", "d": "v"}
+	if len(got) != len(expect) {
+		t.Errorf("Got %v (expected %v)", got, expect)
+	}
+	for tag, title := range expect {
+		if got[tag].Title != title {
+			t.Errorf("On case %v, got %v (expected %v)", tag, got[tag].Title, title)
+		}
+	}
+}
+
+func TestInbox(t *testing.T) {
+	l, err := FromReader(strings.NewReader(`buy milk
+buy milk @store
+buy bread +errand
+buy eggs 2014-3-5
+buy jam s:2014-3-1
+x buy tea
+(A) buy cheese id:3
+`))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+
+	var got []string
+	for _, todo := range l.Inbox() {
+		got = append(got, todo.Title)
+	}
+	if expect := []string{"buy milk", "buy cheese"}; !equalStrings(got, expect) {
+		t.Errorf("Got %v (expected %v)", got, expect)
+	}
+}
+
+func TestBlocked(t *testing.T) {
+	defer func(old string) { BlockedTag = old }(BlockedTag)
+
+	l, err := FromReader(strings.NewReader("a +waiting\nb +work\nc +blocked\n"))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+
+	cases := []struct {
+		tag    string
+		expect []string
+	}{
+		{"waiting", []string{"b", "c"}},
+		{"blocked", []string{"a", "b"}},
+		{"none", []string{"a", "b", "c"}},
+	}
+
+	for _, cas := range cases {
+		BlockedTag = cas.tag
+		var got []string
+		for _, todo := range l.FilterUnblocked() {
+			got = append(got, todo.Title)
+		}
+		if !equalStrings(got, cas.expect) {
+			t.Errorf("On case %v, got %v (expected %v)", cas.tag, got, cas.expect)
+		}
+	}
+
+	BlockedTag = "waiting"
+	if !l[0].Blocked() || l[1].Blocked() {
+		t.Errorf("Got %v %v for Blocked", l[0].Blocked(), l[1].Blocked())
+	}
+}
+
+func TestFrequencies(t *testing.T) {
+	l, err := FromReader(strings.NewReader(`a +b +c @home
+b +c +a @work
+c +d @work @home
+d +b @phone
+`))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+
+	expect := []TagCount{{"b", 2}, {"c", 2}, {"a", 1}, {"d", 1}}
+	if got := l.TagFrequencies(); !reflect.DeepEqual(got, expect) {
+		t.Errorf("Got %v (expected %v)", got, expect)
+	}
+	expect = []TagCount{{"home", 2}, {"work", 2}, {"phone", 1}}
+	if got := l.ContextFrequencies(); !reflect.DeepEqual(got, expect) {
+		t.Errorf("Got %v (expected %v)", got, expect)
+	}
+	if got := TaskList(nil).TagFrequencies(); len(got) != 0 {
+		t.Errorf("Got %v for an empty list", got)
+	}
+}
+
+func TestDuplicateTitles(t *testing.T) {
+	l, err := FromReader(strings.NewReader(`Buy milk @store
+call mom
+buy MILK +errand
+x buy milk
+call dad
+Call Dad 2014-3-5
+write report
+`))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+
+	got := l.DuplicateTitles()
+	expect := map[string][]int{"buy milk": {1, 3, 4}, "call dad": {5, 6}}
+	if len(got) != len(expect) {
+		t.Errorf("Got %v (expected %v)", got, expect)
+	}
+	for title, lines := range expect {
+		var gotLines []int
+		for _, todo := range got[title] {
+			gotLines = append(gotLines, todo.index)
+		}
+		if !reflect.DeepEqual(gotLines, lines) {
+			t.Errorf("On case %v, got lines %v (expected %v)", title, gotLines, lines)
+		}
+	}
+}
+
+func TestAllTags(t *testing.T) {
+	in := "a +work +b @office\nb +a +work @home\nc\nd +b @office @home\n"
+	l, err := FromReader(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+
+	if got, expect := l.AllTags(), []string{"a", "b", "work"}; !reflect.DeepEqual(got, expect) {
+		t.Errorf("Got tags %v, expected %v", got, expect)
+	}
+	if got, expect := l.AllContexts(), []string{"home", "office"}; !reflect.DeepEqual(got, expect) {
+		t.Errorf("Got contexts %v, expected %v", got, expect)
+	}
+	if got := l[2:3].AllTags(); len(got) != 0 {
+		t.Errorf("Got tags %v, expected none", got)
+	}
+}
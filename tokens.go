@@ -0,0 +1,252 @@
+// Copyright 2014 Ethan Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package todo
+
+import (
+	"sort"
+	"strings"
+)
+
+// A TokenKind identifies the part of a task a Token represents.
+type TokenKind int
+
+const (
+	DoneToken TokenKind = iota
+	PriorityToken
+	CompletedDateToken
+	CreatedDateToken
+	TitleToken
+	DueDateToken
+	StartDateToken
+	RecurrenceToken
+	ContextToken
+	TagToken
+	MetaToken
+	FlagToken
+)
+
+// A Token is a piece of the unparsed form of a task.
+type Token struct {
+	Kind TokenKind
+	Text string
+}
+
+// Tokens returns the pieces of the task's unparsed form, in order.
+// Joining their text with spaces gives UnParse, without any notes.
+// The title is a single token, and may itself contain spaces.
+func (t Task) Tokens() []Token {
+	return Parser{}.Tokens(t)
+}
+
+// Tokens is like Task.Tokens, but uses the parser's date layout and
+// sigils, as Parser.UnParse does.
+func (p Parser) Tokens(t Task) []Token {
+	var ret []Token
+	add := func(kind TokenKind, text string) {
+		ret = append(ret, Token{kind, text})
+	}
+
+	if t.Done {
+		add(DoneToken, "x")
+	}
+	if t.Priority != 0 {
+		add(PriorityToken, "("+string(t.Priority)+")")
+	}
+	if t.Done && !t.Completed.IsZero() {
+		add(CompletedDateToken, t.Completed.Format(p.layout()))
+		if !t.Created.IsZero() {
+			add(CreatedDateToken, t.Created.Format(p.layout()))
+		}
+	}
+	if t.Flagged {
+		add(FlagToken, "*")
+	}
+	if t.HasTitle() {
+		first := len(ret) == 0
+		afterDone := len(ret) == 1 && ret[0].Kind == DoneToken
+		words := strings.Split(t.Title, " ")
+		for i, w := range words {
+			// the first word may also be mistaken for a leading marker
+			_, pri := parsePriority(w)
+			leading := i == 0 && (first && (p.isDoneMarker(w) || strings.HasPrefix(w, "#")) || (first || afterDone) && pri || w == "*")
+			if p.needsEscape(w) || leading {
+				words[i] = `\` + w
+			}
+		}
+		add(TitleToken, strings.Join(words, " "))
+	}
+	// with no title, a due date right after the done marker or
+	// completion date would be read as a completion or creation date,
+	// so it goes last, and is written as due: if nothing precedes it
+	lead := len(ret)
+	dueLater := t.Done && !t.HasTitle()
+	addDue := func() {
+		switch {
+		case t.dueTime:
+			add(DueDateToken, "due:"+formatDateTime(t.Due))
+		case dueLater && len(ret) == lead && t.Created.IsZero() && !t.Flagged:
+			add(DueDateToken, "due:"+t.Due.Format(p.layout()))
+		default:
+			add(DueDateToken, t.Due.Format(p.layout()))
+		}
+	}
+	if !t.Due.IsZero() && !dueLater {
+		addDue()
+	}
+	if !t.Start.IsZero() {
+		if t.startTime {
+			add(StartDateToken, "s:"+formatDateTime(t.Start))
+		} else {
+			add(StartDateToken, "s:"+t.Start.Format(p.layout()))
+		}
+	}
+	if !t.Recurrence.IsZero() {
+		add(RecurrenceToken, "rec:"+t.Recurrence.String())
+	}
+
+	contexts, tags := t.Contexts, t.Tags
+	if p.SortTagsContexts {
+		contexts = append([]string(nil), contexts...)
+		tags = append([]string(nil), tags...)
+		sort.Strings(contexts)
+		sort.Strings(tags)
+	}
+
+	for _, context := range contexts {
+		add(ContextToken, string(p.contextSigil())+context)
+	}
+
+	for _, tag := range tags {
+		add(TagToken, string(p.tagSigil())+tag)
+	}
+
+	keys := make([]string, 0, len(t.Meta))
+	for k := range t.Meta {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		add(MetaToken, k+":"+t.Meta[k])
+	}
+	if !t.Due.IsZero() && dueLater {
+		addDue()
+	}
+
+	return ret
+}
+
+// UnParseOrdered is like UnParse, but keeps the tokens of a parsed
+// task where they were in the line: the title's words, dates,
+// contexts, tags and metadata appear in their original relative
+// positions. Tokens added since parsing go at the end. If the task
+// was not parsed, or its tokens can't be kept in place without
+// changing its meaning, it returns UnParse.
+func (t Task) UnParseOrdered() string {
+	if len(t.order) == 0 {
+		return t.UnParse()
+	}
+
+	var out []Token
+	tokens := t.Tokens()
+	used := make([]bool, len(tokens))
+	var words []string
+	for i, tok := range tokens {
+		switch tok.Kind {
+		case DoneToken, PriorityToken, CompletedDateToken, CreatedDateToken, FlagToken:
+			out = append(out, tok)
+			used[i] = true
+		case TitleToken:
+			words = strings.Split(tok.Text, " ")
+			used[i] = true
+		}
+	}
+	lead := len(out)
+
+	titleSlots := 0
+	for _, slot := range t.order {
+		if slot.Kind == TitleToken {
+			titleSlots++
+		}
+	}
+	// a title that no longer fits the slots goes in the first one
+	if titleSlots > 0 && len(words) != titleSlots {
+		words = []string{strings.Join(words, " ")}
+	}
+	if titleSlots == 0 && len(words) > 0 {
+		out = append(out, Token{TitleToken, strings.Join(words, " ")})
+	}
+
+	for _, slot := range t.order {
+		if slot.Kind == TitleToken {
+			if len(words) > 0 {
+				out = append(out, Token{TitleToken, words[0]})
+				words = words[1:]
+			}
+			continue
+		}
+		key := ""
+		if slot.Kind == MetaToken {
+			key = slot.Text[:strings.Index(slot.Text, ":")+1]
+		}
+		for i, tok := range tokens {
+			if !used[i] && tok.Kind == slot.Kind && strings.HasPrefix(tok.Text, key) {
+				out = append(out, tok)
+				used[i] = true
+				break
+			}
+		}
+	}
+	for i, tok := range tokens {
+		if !used[i] {
+			out = append(out, tok)
+		}
+	}
+
+	// a due date right after the done marker would be read as the
+	// completion or creation date
+	if t.Done && t.Created.IsZero() && len(out) > lead && out[lead].Kind == DueDateToken {
+		return t.UnParse()
+	}
+
+	texts := make([]string, len(out))
+	for i, tok := range out {
+		texts[i] = tok.Text
+	}
+	return t.withNotes(strings.Join(texts, " "))
+}
+
+// needsEscape reports whether word, appearing in a title, would be
+// parsed as something else unless escaped with a backslash.
+func (p Parser) needsEscape(word string) bool {
+	if len(word) < 2 {
+		return false
+	}
+	if word[0] == '\\' || word[0] == p.contextSigil() || word[0] == p.tagSigil() {
+		return true
+	}
+	if _, err := p.date(word); err == nil {
+		return true
+	}
+	if _, ok := relativeDate(word); ok {
+		return true
+	}
+	if strings.HasPrefix(word, "due:") {
+		if _, err := dateTime(word[4:]); err == nil {
+			return true
+		}
+	}
+	if strings.HasPrefix(word, "s:") {
+		_, err := p.date(word[2:])
+		if err != nil {
+			_, err = dateTime(word[2:])
+		}
+		return err == nil
+	}
+	if strings.HasPrefix(word, "rec:") {
+		_, err := parseRecurrence(word[4:])
+		return err == nil
+	}
+	return isMeta(word)
+}
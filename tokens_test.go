@@ -0,0 +1,162 @@
+// Copyright 2014 Ethan Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package todo
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTokens(t *testing.T) {
+	todo, err := Parse("x (A) 2014-1-2 2013-12-30 call mom 2014-1-1 s:2013-12-31 rec:1w @phone +family pri:2")
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+
+	expect := []Token{
+		{DoneToken, "x"},
+		{PriorityToken, "(A)"},
+		{CompletedDateToken, "2014-1-2"},
+		{CreatedDateToken, "2013-12-30"},
+		{TitleToken, "call mom"},
+		{DueDateToken, "2014-1-1"},
+		{StartDateToken, "s:2013-12-31"},
+		{RecurrenceToken, "rec:1w"},
+		{ContextToken, "@phone"},
+		{TagToken, "+family"},
+		{MetaToken, "pri:2"},
+	}
+	if got := todo.Tokens(); !reflect.DeepEqual(got, expect) {
+		t.Errorf("Got %v, expected %v", got, expect)
+	}
+
+	todo, err = Parse("2014-1-1 @home")
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+	expect = []Token{{DueDateToken, "2014-1-1"}, {ContextToken, "@home"}}
+	if got := todo.Tokens(); !reflect.DeepEqual(got, expect) {
+		t.Errorf("Got %v, expected %v", got, expect)
+	}
+}
+
+func TestEscape(t *testing.T) {
+	cases := []struct {
+		in    string
+		title string
+	}{
+		{`price is \+5`, "price is +5"},
+		{`email \@foo @ 5pm`, "email @foo @ 5pm"},
+		{`\x marks the spot`, "x marks the spot"},
+		{`\(A) is a grade`, "(A) is a grade"},
+		{`x \(A) is a grade`, "(A) is a grade"},
+		{`x \2014-1-2 was the day`, "2014-1-2 was the day"},
+		{`see \s:2014-1-2 and \rec:1w and \id:3`, "see s:2014-1-2 and rec:1w and id:3"},
+		{`\\o/ \today`, `\o/ today`},
+		{`a \ b`, `a \ b`},
+		{`(A) x`, "x"},
+		{`x x`, "x"},
+	}
+
+	for _, cas := range cases {
+		todo, err := Parse(cas.in)
+		if err != nil {
+			t.Errorf("On case %v, unexpected parse error %v", cas.in, err)
+			continue
+		}
+		if todo.Title != cas.title {
+			t.Errorf("On case %v, got title %q (expected %q)", cas.in, todo.Title, cas.title)
+		}
+		if len(todo.Tags) != 0 || len(todo.Contexts) != 0 || !todo.Due.IsZero() || todo.Priority != 0 && cas.in[0] != '(' {
+			t.Errorf("On case %v, escaped token was parsed: %v", cas.in, todo.UnParse())
+		}
+		if got := todo.UnParse(); got != cas.in {
+			t.Errorf("On case %v, unparsed to %v", cas.in, got)
+		}
+	}
+
+	built := Task{Title: "+5 @home x"}
+	again, err := Parse(built.UnParse())
+	if err != nil || again.Title != built.Title {
+		t.Errorf("Title %q did not round trip through %v", built.Title, built.UnParse())
+	}
+}
+
+func TestSortTagsContexts(t *testing.T) {
+	a, err := Parse("foo +zeta +alpha @work @home +mid")
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+	b, err := Parse("foo @home +alpha +mid @work +zeta")
+	if err != nil {
+		t.Fatalf("unexpected parse error %v", err)
+	}
+
+	if a.UnParse() == b.UnParse() {
+		t.Errorf("Got the same unsorted output %v for both", a.UnParse())
+	}
+	if got, expect := a.UnParse(), "foo @work @home +zeta +alpha +mid"; got != expect {
+		t.Errorf("Got %v (expected %v)", got, expect)
+	}
+
+	p := Parser{SortTagsContexts: true}
+	expect := "foo @home @work +alpha +mid +zeta"
+	for _, todo := range []Task{a, b} {
+		if got := p.UnParse(todo); got != expect {
+			t.Errorf("Got %v sorted (expected %v)", got, expect)
+		}
+	}
+	if !equalStrings(a.Tags, []string{"zeta", "alpha", "mid"}) || a.Contexts[0] != "work" {
+		t.Errorf("Sorting changed the task to %v %v", a.Tags, a.Contexts)
+	}
+}
+
+func TestUnParseOrdered(t *testing.T) {
+	cases := []struct {
+		in     string
+		change func(*Task)
+		expect string
+	}{
+		{"buy @store milk 2024-01-01 +errand", nil, "buy @store milk 2024-1-1 +errand"},
+		{"buy @store milk 2024-01-01 +errand", (*Task).Complete, "x 2014-3-5 buy @store milk 2024-1-1 +errand"},
+		{"a:1 +b foo @c bar z:2", func(t *Task) { t.Meta["a"] = "3" }, "a:3 +b foo @c bar z:2"},
+		{"(A) foo @Home bar @home @work", (*Task).NormalizeTagsContexts, "(A) foo @home bar @work"},
+		{"foo @home bar", func(t *Task) { t.Title = "one two three" }, "one two three @home"},
+		{"foo @home bar", func(t *Task) { t.Title = "baz qux" }, "baz @home qux"},
+		{"foo @home", func(t *Task) { t.Tags = []string{"new"} }, "foo @home +new"},
+		{"@home foo pri:B", nil, "(B) @home foo"},
+		{"@home x foo", nil, `@home \x foo`},
+		{"2014-3-1 foo", (*Task).Complete, "x 2014-3-5 foo 2014-3-1"},
+	}
+
+	defer setNow(time.Date(2014, 3, 5, 12, 0, 0, 0, time.Local))()
+	for _, cas := range cases {
+		todo, err := Parse(cas.in)
+		if err != nil {
+			t.Errorf("On case %v, unexpected parse error %v", cas.in, err)
+			continue
+		}
+		if cas.change != nil {
+			cas.change(&todo)
+		}
+		got := todo.UnParseOrdered()
+		if got != cas.expect {
+			t.Errorf("On case %v, got %v (expected %v)", cas.in, got, cas.expect)
+		}
+		again, err := Parse(got)
+		if err != nil || again.UnParse() != todo.UnParse() {
+			t.Errorf("On case %v, %v parsed to %v (expected %v)", cas.in, got, again.UnParse(), todo.UnParse())
+		}
+	}
+
+	built, err := NewTask("foo").Tag("a").Build()
+	if err != nil {
+		t.Fatalf("unexpected build error %v", err)
+	}
+	if got, expect := built.UnParseOrdered(), "foo +a"; got != expect {
+		t.Errorf("Got %v (expected %v)", got, expect)
+	}
+}